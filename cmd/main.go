@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"gin-quickstart/internal/albums"
+	"gin-quickstart/internal/artists"
 	"gin-quickstart/internal/auth"
 	"gin-quickstart/internal/config"
 	"gin-quickstart/internal/db"
+	"gin-quickstart/internal/genres"
 	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/storage"
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,18 +29,65 @@ func main() {
 		log.Fatalf("failed to connect database: %v", err)
 	}
 
+	// Blob storage setup (cover art)
+	blobs, err := storage.NewLocal(Cfg.Storage.Dir)
+	if err != nil {
+		log.Fatalf("failed to initialise blob storage: %v", err)
+	}
+
 	// Auth setup
+	keys, err := auth.NewKeySet(Cfg.App)
+	if err != nil {
+		log.Fatalf("failed to load JWT keys: %v", err)
+	}
 	authRepo := auth.NewRepository(database)
-	authService := auth.NewService(authRepo, Cfg)
+	revocations := auth.NewRevocationStore(authRepo, Cfg.App.RevocationCacheTTL)
+	authService := auth.NewService(authRepo, revocations, keys, Cfg)
 	authHandler := auth.NewHandler(authService)
 
+	// Create the first admin from ADMIN_USERNAME / ADMIN_PASSWORD if there is none yet
+	if created, err := authService.BootstrapAdmin(Cfg.App.AdminUsername, Cfg.App.AdminPassword); err != nil {
+		log.Fatalf("failed to bootstrap admin: %v", err)
+	} else if created {
+		log.Printf("👤 Created initial admin %q", Cfg.App.AdminUsername)
+	}
+
+	// Periodically forget revoked tokens that have expired anyway
+	auth.StartRevocationPurger(context.Background(), revocations, time.Hour)
+
+	// Artists setup
+	artistRepo := artists.NewRepository(database, albums.FollowArtistRename)
+	artistService := artists.NewService(artistRepo)
+	artistHandler := artists.NewHandler(artistService)
+
+	// Genres setup
+	genreRepo := genres.NewRepository(database)
+	genreService := genres.NewService(genreRepo)
+	genreHandler := genres.NewHandler(genreService)
+
 	// Albums setup
 	albumRepo := albums.NewRepository(database)
-	albumService := albums.NewService(albumRepo)
-	albumHandler := albums.NewHandler(albumService)
+	albumService := albums.NewService(albumRepo, artistService, genreService, blobs, Cfg)
+	albumHandler := albums.NewHandler(albumService, Cfg)
+
+	// Periodically purge albums whose trash retention has expired
+	albums.StartTrashPurger(context.Background(), albumService, Cfg.Albums.TrashPurgeInterval)
+
+	// Create router and register feature routes. Errors reported by handlers
+	// are rendered as application/problem+json; internal messages are
+	// hidden in release mode.
+	gin.SetMode(Cfg.App.GinMode)
+	router := gin.New()
+	router.Use(
+		gin.Logger(),
+		middleware.RequestID(),
+		gin.CustomRecovery(middleware.Recover),
+		middleware.Problems(albums.ErrorStatuses, artists.ErrorStatuses, genres.ErrorStatuses),
+	)
+	router.NoRoute(middleware.NoRoute)
 
-	// Create router and register feature routes.
-	router := gin.Default()
+	// Public keys for verifying our tokens
+	authHandler.RegisterWellKnownRoutes(router)
 
 	// API v1 group
 	apiGroup := router.Group("/api/v1")
@@ -46,9 +98,12 @@ func main() {
 
 	// PROTECTED ROUTES
 	protectedGroup := apiGroup.Group("/")
-	protectedGroup.Use(middleware.AuthMiddleware([]byte(Cfg.App.JWTSecret)))
+	protectedGroup.Use(middleware.AuthMiddleware(keys, auth.NewTokenOptions(Cfg.App), revocations))
 	{
+		authHandler.RegisterProtectedRoutes(protectedGroup, middleware.Authorize("admin"))
 		albumHandler.RegisterRoutes(protectedGroup)
+		artistHandler.RegisterRoutes(protectedGroup)
+		genreHandler.RegisterRoutes(protectedGroup)
 	}
 
 	// 4. Start HTTP server.
@@ -0,0 +1,145 @@
+package albums
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// MaxTagLength caps the length of a single tag, in characters.
+const MaxTagLength = 50
+
+// MaxTagFacets caps the number of tags returned by the facets endpoint.
+const MaxTagFacets = 50
+
+// Match modes for the genre and tag filters.
+const (
+	// MatchAny selects albums with at least one of the requested values.
+	MatchAny = "any"
+	// MatchAll selects albums with every requested value.
+	MatchAll = "all"
+)
+
+// Tag is a free-form label attached to albums. Tags are created on first use
+// and serialized as plain strings.
+type Tag struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"uniqueIndex;not null"`
+}
+
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Name)
+}
+
+func (t *Tag) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &t.Name)
+}
+
+// NormalizeTag folds a tag to its stored form: lower case with whitespace
+// collapsed. It returns an empty string for a blank tag.
+func NormalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
+
+// GenreAssignment is the body of PUT /albums/:id/genres.
+type GenreAssignment struct {
+	Genres []string `json:"genres" binding:"required"`
+}
+
+// TagAssignment is the body of PUT /albums/:id/tags.
+type TagAssignment struct {
+	Tags []string `json:"tags" binding:"required"`
+}
+
+// GenreFacet is the number of matching albums in one genre.
+type GenreFacet struct {
+	Slug  string `json:"slug"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// TagFacet is the number of matching albums carrying one tag.
+type TagFacet struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// Facets holds per-genre and per-tag album counts for a listing filter.
+type Facets struct {
+	Genres []GenreFacet `json:"genres"`
+	Tags   []TagFacet   `json:"tags"`
+}
+
+// normalizeTags normalizes and de-duplicates tags, keeping their order.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+	for _, raw := range tags {
+		tag := NormalizeTag(raw)
+		if tag == "" {
+			return nil, fmt.Errorf("%w: tags must not be blank", ErrInvalidAlbum)
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidAlbum, tag, MaxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// parseListParam collects a filter given as repeated and/or comma-separated
+// values (?genre=jazz,blues&genre=soul), applying normalize to each. The
+// values come back sorted and without duplicates, so MatchAll can compare
+// their count with the number of distinct matches.
+func parseListParam(q url.Values, key string, normalize func(string) string) []string {
+	var values []string
+	for _, raw := range q[key] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = normalize(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	slices.Sort(values)
+	return slices.Compact(values)
+}
+
+// parseMatch reads a match mode parameter, defaulting to MatchAny.
+func parseMatch(q url.Values, key string) (string, error) {
+	switch mode := strings.ToLower(q.Get(key)); mode {
+	case "", MatchAny:
+		return MatchAny, nil
+	case MatchAll:
+		return MatchAll, nil
+	default:
+		return "", fmt.Errorf("%s must be %q or %q", key, MatchAny, MatchAll)
+	}
+}
+
+// classifiedAs restricts tx to albums linked to the given values through a
+// join table. With MatchAll every value must be linked, otherwise any one.
+func classifiedAs(tx *gorm.DB, joinTable, fk, table, column string, values []string, match string) *gorm.DB {
+	sub := fmt.Sprintf(
+		"SELECT j.album_id FROM %s AS j JOIN %s AS t ON t.id = j.%s WHERE t.%s IN ?",
+		joinTable, table, fk, column,
+	)
+	if match == MatchAll {
+		return tx.Where("albums.id IN ("+sub+" GROUP BY j.album_id HAVING COUNT(DISTINCT t.id) = ?)", values, len(values))
+	}
+	return tx.Where("albums.id IN ("+sub+")", values)
+}
+
+// preloadClassification loads an album's genres and tags alongside it.
+func preloadClassification(tx *gorm.DB) *gorm.DB {
+	return tx.
+		Preload("Genres", func(db *gorm.DB) *gorm.DB { return db.Order("genres.name ASC") }).
+		Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") })
+}
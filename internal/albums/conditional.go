@@ -0,0 +1,79 @@
+package albums
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listETag derives a weak entity tag for a page of albums rendered with fs in
+// mediaType. It changes whenever an album on the page is added, removed or
+// updated, or the total moves.
+func listETag(albums []Album, total int64, next string, fs Fieldset, mediaType string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d|%s|%s|%s|%s", total, next,
+		strings.Join(fs.Fields, ","), strings.Join(fs.Include, ","), mediaType)
+	for _, a := range albums {
+		fmt.Fprintf(h, "|%d:%d:%d", a.ID, a.Version, a.UpdatedAt.UnixNano())
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// lastModified returns the most recent UpdatedAt among albums.
+func lastModified(albums ...Album) time.Time {
+	var latest time.Time
+	for _, a := range albums {
+		if a.UpdatedAt.After(latest) {
+			latest = a.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// notModified sets the validator headers and, if the request's conditional
+// headers show the client already has this representation, answers
+// 304 Not Modified. It reports whether the response has been written.
+func notModified(c *gin.Context, etag string, modified time.Time) bool {
+	c.Header("ETag", etag)
+	if !modified.IsZero() {
+		c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence over If-Modified-Since (RFC 9110 §13.2.2).
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if !etagListMatches(inm, etag) {
+			return false
+		}
+	} else if ims := c.GetHeader("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		since, err := http.ParseTime(ims)
+		if err != nil || modified.Truncate(time.Second).After(since) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	c.Writer.WriteHeaderNow()
+	return true
+}
+
+// etagListMatches reports whether any tag in an If-None-Match header matches
+// etag using weak comparison, i.e. ignoring the W/ prefix.
+func etagListMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package albums
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register decoders for the accepted cover formats
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CoverFormField is the multipart field carrying the uploaded image.
+const CoverFormField = "cover"
+
+// CoverOriginal names the unscaled cover image.
+const CoverOriginal = "original"
+
+// MaxCoverPixels bounds width×height of an uploaded cover so a small file
+// cannot decompress into an enormous bitmap.
+const MaxCoverPixels = 50_000_000
+
+// thumbnailQuality is the JPEG quality used for generated thumbnails.
+const thumbnailQuality = 85
+
+var (
+	// ErrCoverTooLarge is returned when an upload exceeds the size limit.
+	ErrCoverTooLarge = errors.New("cover image is too large")
+	// ErrUnsupportedCover is returned for uploads that are not JPEG, PNG or GIF.
+	ErrUnsupportedCover = errors.New("cover must be a JPEG, PNG or GIF image")
+	// ErrInvalidCover is returned for images that cannot be decoded or are too big to process.
+	ErrInvalidCover = errors.New("cover image could not be processed")
+	// ErrNoCover is returned when an album has no cover, or not in the requested size.
+	ErrNoCover = errors.New("cover not found")
+)
+
+// coverTypes are the accepted upload types, as sniffed from the content.
+var coverTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// CoverURLs points at an album's cover image and its thumbnails, keyed by
+// their maximum edge in pixels.
+type CoverURLs struct {
+	Original   string            `json:"original"`
+	Thumbnails map[string]string `json:"thumbnails"`
+}
+
+// CoverBlob is an open cover image ready to be served.
+type CoverBlob struct {
+	Body        io.ReadCloser
+	Size        int64
+	ContentType string
+	ETag        string
+	ModTime     time.Time
+}
+
+// coverImage is a validated upload with its generated thumbnails.
+type coverImage struct {
+	hash        string
+	contentType string
+	original    []byte
+	thumbnails  map[int][]byte
+}
+
+// processCover validates an uploaded image and renders a JPEG thumbnail for
+// every size in sizes.
+func processCover(data []byte, sizes []int) (coverImage, error) {
+	// 1. Trust the bytes, not the client's Content-Type
+	contentType := http.DetectContentType(data)
+	if !coverTypes[contentType] {
+		return coverImage{}, ErrUnsupportedCover
+	}
+
+	// 2. Check dimensions before decoding the full bitmap
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return coverImage{}, fmt.Errorf("%w: %v", ErrInvalidCover, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxCoverPixels {
+		return coverImage{}, fmt.Errorf("%w: %dx%d exceeds %d pixels", ErrInvalidCover, cfg.Width, cfg.Height, MaxCoverPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return coverImage{}, fmt.Errorf("%w: %v", ErrInvalidCover, err)
+	}
+
+	// 3. Render thumbnails from a flattened copy (transparency becomes white)
+	flat := flatten(img)
+	cover := coverImage{
+		hash:        contentHash(data),
+		contentType: contentType,
+		original:    data,
+		thumbnails:  make(map[int][]byte, len(sizes)),
+	}
+	for _, size := range sizes {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, fit(flat, size), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return coverImage{}, err
+		}
+		cover.thumbnails[size] = buf.Bytes()
+	}
+	return cover, nil
+}
+
+// contentHash is a short fingerprint of data, used to version cover URLs.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// flatten draws img onto an opaque white RGBA canvas.
+func flatten(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// fit scales src down so neither edge exceeds size, keeping the aspect
+// ratio. Smaller images are returned as they are.
+func fit(src *image.RGBA, size int) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+	dw, dh := size, max(1, h*size/w)
+	if h > w {
+		dw, dh = max(1, w*size/h), size
+	}
+	return downscale(src, dw, dh)
+}
+
+// downscale resizes src to dw×dh by averaging the block of source pixels
+// that falls under each destination pixel (a box filter).
+func downscale(src *image.RGBA, dw, dh int) *image.RGBA {
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0 := y * sh / dh
+		y1 := max((y+1)*sh/dh, y0+1)
+		for x := 0; x < dw; x++ {
+			x0 := x * sw / dw
+			x1 := max((x+1)*sw/dw, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(src.Rect.Min.X+x0, src.Rect.Min.Y+sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint64(src.Pix[i])
+					g += uint64(src.Pix[i+1])
+					b += uint64(src.Pix[i+2])
+					a += uint64(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// coverPrefix is the storage prefix holding every cover blob of an album.
+func coverPrefix(albumID uint) string {
+	return fmt.Sprintf("covers/%d", albumID)
+}
+
+// coverKey is the storage key of one rendition ("original" or a thumbnail
+// size) of the cover with the given content hash.
+func coverKey(albumID uint, hash, rendition string) string {
+	return fmt.Sprintf("%s/%s/%s", coverPrefix(albumID), hash, rendition)
+}
+
+// coverURLs builds the URLs of an album's cover below base, the path the
+// album routes are mounted on. It returns nil if the album has no cover.
+func coverURLs(base string, album Album, sizes []int) *CoverURLs {
+	if album.CoverHash == "" {
+		return nil
+	}
+	url := func(rendition string) string {
+		return fmt.Sprintf("%s/%d/cover/%s?v=%s", base, album.ID, rendition, album.CoverHash)
+	}
+	urls := &CoverURLs{
+		Original:   url(CoverOriginal),
+		Thumbnails: make(map[string]string, len(sizes)),
+	}
+	for _, size := range sizes {
+		urls.Thumbnails[strconv.Itoa(size)] = url(strconv.Itoa(size))
+	}
+	return urls
+}
@@ -0,0 +1,183 @@
+package albums
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed, tampered with or
+// was issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a keyset-paginated listing. It records the sort
+// it was issued for and the sort-key values of the last row returned.
+type Cursor struct {
+	Sort   string `json:"s"`
+	Values []any  `json:"v"`
+}
+
+// sortKeys returns the effective ORDER BY columns, including the primary key
+// tie-breaker that makes every ordering total.
+func sortKeys(sort []SortField) []SortField {
+	keys := make([]SortField, 0, len(sort)+1)
+	for _, f := range sort {
+		keys = append(keys, f)
+		if f.Column == "id" {
+			return keys
+		}
+	}
+	return append(keys, SortField{Column: "id"})
+}
+
+// sortSignature renders keys back into the "title,-created_at,id" form.
+func sortSignature(keys []SortField) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if k.Desc {
+			parts[i] = "-" + k.Column
+		} else {
+			parts[i] = k.Column
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// newCursor builds the cursor pointing just past album for the given sort.
+func newCursor(sort []SortField, album Album) Cursor {
+	keys := sortKeys(sort)
+	values := make([]any, len(keys))
+	for i, k := range keys {
+		values[i] = columnValue(album, k.Column)
+	}
+	return Cursor{Sort: sortSignature(keys), Values: values}
+}
+
+// columnValue returns the value of a sortable column on album.
+func columnValue(album Album, column string) any {
+	switch column {
+	case "title":
+		return album.Title
+	case "artist":
+		return album.Artist
+	case "created_at":
+		return album.CreatedAt
+	case "updated_at":
+		return album.UpdatedAt
+	default:
+		return album.ID
+	}
+}
+
+// EncodeCursor serialises c and signs it with secret.
+func EncodeCursor(c Cursor, secret []byte) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(signCursor(payload, secret)), nil
+}
+
+// DecodeCursor verifies the signature on raw and checks that it was issued
+// for sort. Values are converted back to their column types.
+func DecodeCursor(raw string, sort []SortField, secret []byte) (Cursor, error) {
+	payloadPart, sigPart, ok := strings.Cut(raw, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil || !hmac.Equal(sig, signCursor(payload, secret)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	keys := sortKeys(sort)
+	if c.Sort != sortSignature(keys) || len(c.Values) != len(keys) {
+		return Cursor{}, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidCursor)
+	}
+	for i, k := range keys {
+		if c.Values[i], err = decodeColumnValue(k.Column, c.Values[i]); err != nil {
+			return Cursor{}, ErrInvalidCursor
+		}
+	}
+	return c, nil
+}
+
+// decodeColumnValue converts a JSON-decoded cursor value back to the Go type of column.
+func decodeColumnValue(column string, v any) (any, error) {
+	switch column {
+	case "title", "artist":
+		s, ok := v.(string)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		return s, nil
+	case "created_at", "updated_at":
+		s, ok := v.(string)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	default:
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		id, err := strconv.ParseUint(n.String(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return uint(id), nil
+	}
+}
+
+func signCursor(payload, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// applyKeyset restricts tx to rows strictly after c in the given sort order:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... with ">" flipped for DESC keys.
+func applyKeyset(tx *gorm.DB, sort []SortField, c Cursor) *gorm.DB {
+	keys := sortKeys(sort)
+	var (
+		branches []string
+		args     []any
+	)
+	for i, k := range keys {
+		conds := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, keys[j].Column+" = ?")
+			args = append(args, c.Values[j])
+		}
+		op := " > ?"
+		if k.Desc {
+			op = " < ?"
+		}
+		conds = append(conds, k.Column+op)
+		args = append(args, c.Values[i])
+		branches = append(branches, "("+strings.Join(conds, " AND ")+")")
+	}
+	return tx.Where(strings.Join(branches, " OR "), args...)
+}
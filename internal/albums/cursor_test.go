@@ -0,0 +1,95 @@
+package albums
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	secret := []byte("cursor-secret")
+	created := time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	album := Album{Title: "Kind of Blue", Artist: "Miles Davis"}
+	album.ID, album.CreatedAt = 42, created
+
+	tests := []struct {
+		name string
+		sort []SortField
+		want []any
+	}{
+		{"default sort", nil, []any{uint(42)}},
+		{"title then id", []SortField{{Column: "title"}}, []any{"Kind of Blue", uint(42)}},
+		{"descending time", []SortField{{Column: "created_at", Desc: true}}, []any{created, uint(42)}},
+		{"explicit id ends the keys", []SortField{{Column: "id", Desc: true}, {Column: "title"}}, []any{uint(42)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := EncodeCursor(newCursor(tt.sort, album), secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := DecodeCursor(raw, tt.sort, secret)
+			if err != nil {
+				t.Fatalf("DecodeCursor: %v", err)
+			}
+			if len(c.Values) != len(tt.want) {
+				t.Fatalf("values = %v, want %v", c.Values, tt.want)
+			}
+			for i, want := range tt.want {
+				if wantTime, ok := want.(time.Time); ok {
+					if got, ok := c.Values[i].(time.Time); !ok || !got.Equal(wantTime) {
+						t.Errorf("value %d = %v, want %v", i, c.Values[i], want)
+					}
+				} else if c.Values[i] != want {
+					t.Errorf("value %d = %#v, want %#v", i, c.Values[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	secret := []byte("cursor-secret")
+	sort := []SortField{{Column: "title"}}
+	album := Album{Title: "Giant Steps"}
+	album.ID = 7
+	valid, err := EncodeCursor(newCursor(sort, album), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(valid, ".")
+	resign := func(json string) string {
+		p := []byte(json)
+		return base64.RawURLEncoding.EncodeToString(p) + "." + base64.RawURLEncoding.EncodeToString(signCursor(p, secret))
+	}
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"title,id","v":["Giant Steps",8]}`)) + "." + sig
+
+	tests := []struct {
+		name   string
+		raw    string
+		sort   []SortField
+		secret []byte
+	}{
+		{"no signature", payload, sort, secret},
+		{"not base64", "!!!." + sig, sort, secret},
+		{"tampered payload", tampered, sort, secret},
+		{"truncated signature", payload + "." + sig[:10], sort, secret},
+		{"signed with another secret", valid, sort, []byte("other-secret")},
+		{"issued for another sort", valid, []SortField{{Column: "title", Desc: true}}, secret},
+		{"issued for the default sort", valid, nil, secret},
+		{"re-signed with too few values", resign(`{"s":"title,id","v":["Giant Steps"]}`), sort, secret},
+		{"re-signed with a wrongly typed value", resign(`{"s":"title,id","v":[1,7]}`), sort, secret},
+		{"re-signed with a negative id", resign(`{"s":"title,id","v":["Giant Steps",-7]}`), sort, secret},
+		{"re-signed with a bad time", resign(`{"s":"created_at,id","v":["yesterday",7]}`), []SortField{{Column: "created_at"}}, secret},
+		{"re-signed with invalid JSON", resign(`{"s":`), sort, secret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.raw, tt.sort, tt.secret); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package albums
+
+import "time"
+
+// The types below are the public shape of the album endpoints. Handlers bind
+// requests into them and map models onto them, so columns and GORM internals
+// can change without changing the API. Field names are snake_case and
+// timestamps RFC 3339 in UTC.
+
+// AlbumRequest is the body of POST /albums and PUT /albums/:id.
+type AlbumRequest struct {
+	Title string `json:"title" binding:"required"`
+	// Artist is the credited artist's display name. Clients may send either
+	// artist_id or, for backward compatibility, just the name.
+	Artist   string `json:"artist" binding:"required_without=ArtistID"`
+	ArtistID *uint  `json:"artist_id" binding:"required_without=Artist"`
+}
+
+// toAlbum maps the request onto a new Album.
+func (r AlbumRequest) toAlbum() Album {
+	return Album{Title: r.Title, Artist: r.Artist, ArtistID: r.ArtistID}
+}
+
+// AlbumResponse is an album as returned by the API. Relations are only
+// present when they were loaded.
+type AlbumResponse struct {
+	ID           uint            `json:"id"`
+	Title        string          `json:"title"`
+	Artist       string          `json:"artist"`
+	ArtistID     *uint           `json:"artist_id"`
+	ArtistDetail *ArtistResponse `json:"artist_detail,omitempty"`
+	// Version is the album's ETag for optimistic locking.
+	Version   uint            `json:"version"`
+	Genres    []GenreResponse `json:"genres,omitempty"`
+	Tags      []string        `json:"tags,omitempty"`
+	Tracks    []TrackResponse `json:"tracks,omitempty"`
+	Cover     *CoverURLs      `json:"cover,omitempty"`
+	CreatedAt string          `json:"created_at"`
+	UpdatedAt string          `json:"updated_at"`
+}
+
+// NewAlbumResponse maps an album and its loaded relations. Cover URLs depend
+// on where the routes are mounted and are filled in by the handler.
+func NewAlbumResponse(album Album) AlbumResponse {
+	resp := AlbumResponse{
+		ID:        album.ID,
+		Title:     album.Title,
+		Artist:    album.Artist,
+		ArtistID:  album.ArtistID,
+		Version:   album.Version,
+		Tracks:    NewTrackResponses(album.Tracks),
+		CreatedAt: formatTime(album.CreatedAt),
+		UpdatedAt: formatTime(album.UpdatedAt),
+	}
+	if a := album.ArtistDetail; a != nil {
+		resp.ArtistDetail = &ArtistResponse{ID: a.ID, Name: a.Name}
+	}
+	if album.Genres != nil {
+		resp.Genres = make([]GenreResponse, len(album.Genres))
+		for i, g := range album.Genres {
+			resp.Genres[i] = GenreResponse{ID: g.ID, Name: g.Name, Slug: g.Slug}
+		}
+	}
+	if album.Tags != nil {
+		resp.Tags = make([]string, len(album.Tags))
+		for i, t := range album.Tags {
+			resp.Tags[i] = t.Name
+		}
+	}
+	return resp
+}
+
+// ArtistResponse is the credited artist embedded with ?include=artist_detail.
+type ArtistResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// GenreResponse is a genre assigned to an album.
+type GenreResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// TrackRequest is the body of POST /albums/:id/tracks and
+// PUT /albums/:id/tracks/:trackId.
+type TrackRequest struct {
+	DiscNumber uint   `json:"disc_number"`
+	Position   uint   `json:"position"`
+	Title      string `json:"title" binding:"required"`
+	Duration   uint   `json:"duration"` // in seconds
+	ISRC       string `json:"isrc"`
+}
+
+// toTrack maps the request onto a new Track.
+func (r TrackRequest) toTrack() Track {
+	return Track{DiscNumber: r.DiscNumber, Position: r.Position, Title: r.Title, Duration: r.Duration, ISRC: r.ISRC}
+}
+
+// TrackResponse is a track as returned by the API.
+type TrackResponse struct {
+	ID         uint   `json:"id"`
+	AlbumID    uint   `json:"album_id"`
+	DiscNumber uint   `json:"disc_number"`
+	Position   uint   `json:"position"`
+	Title      string `json:"title"`
+	Duration   uint   `json:"duration"` // in seconds
+	ISRC       string `json:"isrc"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// NewTrackResponse maps a track.
+func NewTrackResponse(t Track) TrackResponse {
+	return TrackResponse{
+		ID:         t.ID,
+		AlbumID:    t.AlbumID,
+		DiscNumber: t.DiscNumber,
+		Position:   t.Position,
+		Title:      t.Title,
+		Duration:   t.Duration,
+		ISRC:       t.ISRC,
+		CreatedAt:  formatTime(t.CreatedAt),
+		UpdatedAt:  formatTime(t.UpdatedAt),
+	}
+}
+
+// NewTrackResponses maps a track listing, keeping nil as nil so unloaded
+// tracks are left out of album responses.
+func NewTrackResponses(tracks []Track) []TrackResponse {
+	if tracks == nil {
+		return nil
+	}
+	out := make([]TrackResponse, len(tracks))
+	for i, t := range tracks {
+		out[i] = NewTrackResponse(t)
+	}
+	return out
+}
+
+// SearchResultResponse is an album matched by a search, with its relevance
+// score and highlighted snippets.
+type SearchResultResponse struct {
+	AlbumResponse
+	Rank            float64 `json:"rank"`
+	TitleHighlight  string  `json:"title_highlight,omitempty"`
+	ArtistHighlight string  `json:"artist_highlight,omitempty"`
+}
+
+// NewSearchResultResponse maps a search result.
+func NewSearchResultResponse(r SearchResult) SearchResultResponse {
+	return SearchResultResponse{
+		AlbumResponse:   NewAlbumResponse(r.Album),
+		Rank:            r.Rank,
+		TitleHighlight:  r.TitleHighlight,
+		ArtistHighlight: r.ArtistHighlight,
+	}
+}
+
+// RevisionResponse is one entry in an album's change history with its
+// field-level diff.
+type RevisionResponse struct {
+	ID        uint              `json:"id"`
+	AlbumID   uint              `json:"album_id"`
+	Action    string            `json:"action"`
+	ActorID   uint              `json:"actor_id"`
+	Before    *SnapshotResponse `json:"before"`
+	After     *SnapshotResponse `json:"after"`
+	Changes   []FieldChange     `json:"changes"`
+	CreatedAt string            `json:"created_at"`
+}
+
+// SnapshotResponse is the state of an album recorded in a revision. Genres
+// and Tags are null for revisions recorded before they were tracked.
+type SnapshotResponse struct {
+	Title     string   `json:"title"`
+	Artist    string   `json:"artist"`
+	ArtistID  *uint    `json:"artist_id,omitempty"`
+	Genres    []string `json:"genres"`
+	Tags      []string `json:"tags"`
+	CoverHash string   `json:"cover_hash,omitempty"`
+	CoverType string   `json:"cover_type,omitempty"`
+	Version   uint     `json:"version"`
+}
+
+// NewRevisionResponse maps a revision and its diff.
+func NewRevisionResponse(r RevisionWithDiff) RevisionResponse {
+	return RevisionResponse{
+		ID:        r.ID,
+		AlbumID:   r.AlbumID,
+		Action:    r.Action,
+		ActorID:   r.ActorID,
+		Before:    newSnapshotResponse(r.Before),
+		After:     newSnapshotResponse(r.After),
+		Changes:   r.Changes,
+		CreatedAt: formatTime(r.CreatedAt),
+	}
+}
+
+// NewRevisionResponses maps a page of history.
+func NewRevisionResponses(revisions []RevisionWithDiff) []RevisionResponse {
+	out := make([]RevisionResponse, len(revisions))
+	for i, r := range revisions {
+		out[i] = NewRevisionResponse(r)
+	}
+	return out
+}
+
+func newSnapshotResponse(s *Snapshot) *SnapshotResponse {
+	if s == nil {
+		return nil
+	}
+	return &SnapshotResponse{
+		Title: s.Title, Artist: s.Artist, ArtistID: s.ArtistID,
+		Genres: s.Genres, Tags: s.Tags, CoverHash: s.CoverHash, CoverType: s.CoverType,
+		Version: s.Version,
+	}
+}
+
+// formatTime renders a timestamp the way responses and exports carry it.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,99 @@
+package albums
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gin-quickstart/internal/negotiate"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPreconditionRequired is returned when a write omits If-Match while
+	// the server is configured to require it.
+	ErrPreconditionRequired = errors.New("If-Match header is required")
+	// ErrVersionMismatch is returned when If-Match does not match the album's
+	// current version, i.e. someone else changed it first.
+	ErrVersionMismatch = errors.New("album has been modified since it was fetched")
+)
+
+// IfMatch is the parsed If-Match precondition of a write request.
+type IfMatch struct {
+	Present  bool   // the header was sent
+	Any      bool   // the header was "*"
+	Versions []uint // versions listed as strong entity tags
+}
+
+// ParseIfMatch parses an If-Match header. Weak or malformed entity tags are
+// ignored, since If-Match requires strong comparison (RFC 9110 §13.1.1).
+func ParseIfMatch(header string) IfMatch {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return IfMatch{}
+	}
+	if header == "*" {
+		return IfMatch{Present: true, Any: true}
+	}
+
+	p := IfMatch{Present: true}
+	for _, tag := range strings.Split(header, ",") {
+		if v, ok := parseVersionETag(strings.TrimSpace(tag)); ok {
+			p.Versions = append(p.Versions, v)
+		}
+	}
+	return p
+}
+
+// matches reports whether version satisfies the precondition.
+func (p IfMatch) matches(version uint) bool {
+	if !p.Present || p.Any {
+		return true
+	}
+	for _, v := range p.Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedVersions returns the versions an UPDATE must match, or nil when
+// the write is unconditional.
+func (p IfMatch) expectedVersions() []uint {
+	if !p.Present || p.Any {
+		return nil
+	}
+	return p.Versions
+}
+
+// VersionETag renders an album version as a strong entity tag.
+func VersionETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// variantETag renders the strong entity tag of one representation of an
+// album version. The full JSON document is tagged VersionETag(version);
+// other fieldsets and media types get the version followed by a digest of
+// both, so that no two representations share a strong tag.
+func variantETag(version uint, fs Fieldset, mediaType string) string {
+	if fs.Fields == nil && len(fs.Include) == 0 && mediaType == string(negotiate.JSON) {
+		return VersionETag(version)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fs.Fields, ",") + "|" + strings.Join(fs.Include, ",") + "|" + mediaType))
+	return `"` + strconv.FormatUint(uint64(version), 10) + "-" + hex.EncodeToString(sum[:4]) + `"`
+}
+
+// parseVersionETag parses a strong entity tag produced by VersionETag or
+// variantETag.
+func parseVersionETag(tag string) (uint, bool) {
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	version, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+	v, err := strconv.ParseUint(version, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(v), true
+}
@@ -0,0 +1,86 @@
+package albums
+
+import (
+	"gin-quickstart/internal/negotiate"
+	"reflect"
+	"testing"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		header string
+		want   IfMatch
+	}{
+		{``, IfMatch{}},
+		{`  `, IfMatch{}},
+		{`*`, IfMatch{Present: true, Any: true}},
+		{`"3"`, IfMatch{Present: true, Versions: []uint{3}}},
+		{`"3", "5"`, IfMatch{Present: true, Versions: []uint{3, 5}}},
+		{`"3-1a2b3c4d"`, IfMatch{Present: true, Versions: []uint{3}}},
+		{`W/"3"`, IfMatch{Present: true}},
+		{`W/"3", "4"`, IfMatch{Present: true, Versions: []uint{4}}},
+		{`3`, IfMatch{Present: true}},
+		{`"three"`, IfMatch{Present: true}},
+		{`"-3"`, IfMatch{Present: true}},
+		{`"`, IfMatch{Present: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := ParseIfMatch(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseIfMatch(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIfMatchMatches(t *testing.T) {
+	tests := []struct {
+		header  string
+		version uint
+		want    bool
+	}{
+		{``, 4, true},
+		{`*`, 4, true},
+		{`"4"`, 4, true},
+		{`"3", "4"`, 4, true},
+		{`"3"`, 4, false},
+		{`W/"4"`, 4, false},
+	}
+	for _, tt := range tests {
+		if got := ParseIfMatch(tt.header).matches(tt.version); got != tt.want {
+			t.Errorf("ParseIfMatch(%q).matches(%d) = %v, want %v", tt.header, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestVariantETag(t *testing.T) {
+	jsonType := string(negotiate.JSON)
+	full := variantETag(7, Fieldset{}, jsonType)
+	if full != `"7"` {
+		t.Fatalf("full JSON tag = %s, want \"7\"", full)
+	}
+
+	variants := map[string]string{
+		"full JSON":  full,
+		"XML":        variantETag(7, Fieldset{}, "application/xml"),
+		"text/xml":   variantETag(7, Fieldset{}, "text/xml"),
+		"fields":     variantETag(7, Fieldset{Fields: []string{"title"}}, jsonType),
+		"fields XML": variantETag(7, Fieldset{Fields: []string{"title"}}, "application/xml"),
+		"include":    variantETag(7, Fieldset{Include: []string{"tracks"}}, jsonType),
+	}
+	seen := map[string]string{}
+	for name, tag := range variants {
+		if other, ok := seen[tag]; ok {
+			t.Errorf("%s and %s share the tag %s", name, other, tag)
+		}
+		seen[tag] = name
+
+		if v, ok := parseVersionETag(tag); !ok || v != 7 {
+			t.Errorf("parseVersionETag(%s) = %d, %v, want 7", tag, v, ok)
+		}
+	}
+
+	if variantETag(7, Fieldset{}, "application/xml") == variantETag(8, Fieldset{}, "application/xml") {
+		t.Error("tags of different versions are equal")
+	}
+}
@@ -0,0 +1,288 @@
+package albums
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ExportBatchSize is the number of albums loaded from the database at a time
+// while exporting.
+const ExportBatchSize = 500
+
+// Export formats accepted by GET /albums/export.
+const (
+	ExportCSV    = "csv"
+	ExportNDJSON = "ndjson"
+	ExportXLSX   = "xlsx"
+)
+
+// exportColumns are the exported fields, in order. The first five are the
+// import columns, in the same CSV form.
+var exportColumns = []string{"title", "artist", "artist_id", "genres", "tags", "id", "version", "created_at", "updated_at"}
+
+// exportRecord is the flat view of an album used by every export format.
+type exportRecord struct {
+	Title     string   `json:"title"`
+	Artist    string   `json:"artist"`
+	ArtistID  *uint    `json:"artist_id"`
+	Genres    []string `json:"genres"`
+	Tags      []string `json:"tags"`
+	ID        uint     `json:"id"`
+	Version   uint     `json:"version"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+// newExportRecord flattens an album response, so exports carry the same
+// values as the API.
+func newExportRecord(album AlbumResponse) exportRecord {
+	rec := exportRecord{
+		Title:     album.Title,
+		Artist:    album.Artist,
+		ArtistID:  album.ArtistID,
+		Genres:    make([]string, 0, len(album.Genres)),
+		Tags:      make([]string, 0, len(album.Tags)),
+		ID:        album.ID,
+		Version:   album.Version,
+		CreatedAt: album.CreatedAt,
+		UpdatedAt: album.UpdatedAt,
+	}
+	for _, g := range album.Genres {
+		rec.Genres = append(rec.Genres, g.Slug)
+	}
+	rec.Tags = append(rec.Tags, album.Tags...)
+	return rec
+}
+
+// cells renders the record as strings, in exportColumns order.
+func (r exportRecord) cells() []string {
+	artistID := ""
+	if r.ArtistID != nil {
+		artistID = strconv.FormatUint(uint64(*r.ArtistID), 10)
+	}
+	return []string{
+		r.Title,
+		r.Artist,
+		artistID,
+		strings.Join(r.Genres, csvListSeparator),
+		strings.Join(r.Tags, csvListSeparator),
+		strconv.FormatUint(uint64(r.ID), 10),
+		strconv.FormatUint(uint64(r.Version), 10),
+		r.CreatedAt,
+		r.UpdatedAt,
+	}
+}
+
+// CSVHeader and CSVRecord let album responses be served as text/csv, using
+// the export columns.
+func (AlbumResponse) CSVHeader() []string {
+	return slices.Clone(exportColumns)
+}
+
+func (a AlbumResponse) CSVRecord() []string {
+	return newExportRecord(a).cells()
+}
+
+// ExportWriter writes albums in one export format. Write may be called any
+// number of times; Close completes the file.
+type ExportWriter interface {
+	Write(albums []Album) error
+	Close() error
+}
+
+// ExportContentType returns the media type of an export format.
+func ExportContentType(format string) string {
+	switch format {
+	case ExportNDJSON:
+		return "application/x-ndjson"
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// ParseExportFormat reads ?format=, defaulting to CSV.
+func ParseExportFormat(raw string) (string, error) {
+	switch format := strings.ToLower(raw); format {
+	case "":
+		return ExportCSV, nil
+	case ExportCSV, ExportNDJSON, ExportXLSX:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q (expected csv, ndjson or xlsx)", raw)
+	}
+}
+
+// NewExportWriter returns an ExportWriter producing format on w.
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return newCSVExport(w)
+	case ExportNDJSON:
+		return &ndjsonExport{enc: json.NewEncoder(w)}, nil
+	case ExportXLSX:
+		return newXLSXExport(w)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// csvExport writes a header row followed by one row per album.
+type csvExport struct {
+	w *csv.Writer
+}
+
+func newCSVExport(w io.Writer) (*csvExport, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportColumns); err != nil {
+		return nil, err
+	}
+	return &csvExport{w: cw}, nil
+}
+
+func (e *csvExport) Write(albums []Album) error {
+	for _, album := range albums {
+		if err := e.w.Write(newExportRecord(NewAlbumResponse(album)).cells()); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExport) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// ndjsonExport writes one JSON object per line.
+type ndjsonExport struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonExport) Write(albums []Album) error {
+	for _, album := range albums {
+		if err := e.enc.Encode(newExportRecord(NewAlbumResponse(album))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ndjsonExport) Close() error {
+	return nil
+}
+
+// The fixed parts of a minimal single-sheet XLSX (Office Open XML) workbook.
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Albums" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxNumericColumns are the columns written as numbers rather than text.
+var xlsxNumericColumns = map[string]bool{"artist_id": true, "id": true, "version": true}
+
+// xlsxExport streams a workbook with a single sheet. The sheet is the last
+// entry of the zip archive, so rows are written as they arrive and nothing
+// is buffered. Cells use inline strings to avoid a shared string table,
+// which would have to be written after all rows are known.
+type xlsxExport struct {
+	zw    *zip.Writer
+	sheet io.Writer
+}
+
+func newXLSXExport(w io.Writer) (*xlsxExport, error) {
+	zw := zip.NewWriter(w)
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, p := range parts {
+		f, err := zw.Create(p.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, p.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &xlsxExport{zw: zw, sheet: sheet}
+	if _, err := io.WriteString(sheet, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	if err := e.writeRow(exportColumns, false); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *xlsxExport) Write(albums []Album) error {
+	for _, album := range albums {
+		if err := e.writeRow(newExportRecord(NewAlbumResponse(album)).cells(), true); err != nil {
+			return err
+		}
+	}
+	return e.zw.Flush()
+}
+
+func (e *xlsxExport) Close() error {
+	if _, err := io.WriteString(e.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return e.zw.Close()
+}
+
+// writeRow writes one <row>. Data rows write the numeric columns as numbers.
+func (e *xlsxExport) writeRow(cells []string, data bool) error {
+	var b strings.Builder
+	b.WriteString("<row>")
+	for i, cell := range cells {
+		if data && xlsxNumericColumns[exportColumns[i]] && cell != "" {
+			b.WriteString(`<c t="n"><v>` + cell + `</v></c>`)
+			continue
+		}
+		b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(&b, []byte(cell)); err != nil {
+			return err
+		}
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString("</row>")
+	_, err := io.WriteString(e.sheet, b.String())
+	return err
+}
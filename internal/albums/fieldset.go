@@ -0,0 +1,233 @@
+package albums
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// albumField is a field that can be requested with ?fields=, named after
+// its key in AlbumResponse.
+type albumField struct {
+	// columns are read from the albums table to fill the field; relations
+	// are preloaded instead.
+	columns []string
+	// csv is the matching export column, if any.
+	csv string
+}
+
+// albumFields whitelists the album fields accepted by ?fields=.
+var albumFields = map[string]albumField{
+	"id":         {columns: []string{"id"}, csv: "id"},
+	"title":      {columns: []string{"title"}, csv: "title"},
+	"artist":     {columns: []string{"artist"}, csv: "artist"},
+	"artist_id":  {columns: []string{"artist_id"}, csv: "artist_id"},
+	"version":    {columns: []string{"version"}, csv: "version"},
+	"genres":     {csv: "genres"},
+	"tags":       {csv: "tags"},
+	"cover":      {columns: []string{"cover_hash", "cover_type"}},
+	"created_at": {columns: []string{"created_at"}, csv: "created_at"},
+	"updated_at": {columns: []string{"updated_at"}, csv: "updated_at"},
+}
+
+// albumIncludes whitelists the related resources accepted by ?include=.
+var albumIncludes = map[string]bool{
+	"tracks":        true,
+	"artist_detail": true,
+}
+
+// alwaysSelected are read even when not requested: the primary key for
+// preloads and cover URLs, version and updated_at for ETags.
+var alwaysSelected = []string{"id", "version", "updated_at"}
+
+// Fieldset chooses what an album response carries: the fields listed in
+// ?fields=id,title (every field if absent) and the related resources
+// embedded with ?include=tracks,artist_detail.
+type Fieldset struct {
+	// Fields are the requested fields; nil means all of them.
+	Fields  []string
+	Include []string
+}
+
+// ParseFieldset reads ?fields= and ?include=, rejecting names that are not
+// whitelisted.
+func ParseFieldset(q url.Values) (Fieldset, error) {
+	var fs Fieldset
+	var err error
+	if q.Get("fields") != "" {
+		if fs.Fields, err = parseNames(q, "fields", func(name string) bool { _, ok := albumFields[name]; return ok }); err != nil {
+			return Fieldset{}, err
+		}
+	}
+	if fs.Include, err = parseNames(q, "include", func(name string) bool { return albumIncludes[name] }); err != nil {
+		return Fieldset{}, err
+	}
+	return fs, nil
+}
+
+// parseNames collects a comma-separated list parameter, checking each name
+// with known.
+func parseNames(q url.Values, key string, known func(string) bool) ([]string, error) {
+	names := parseListParam(q, key, func(s string) string { return strings.ToLower(strings.TrimSpace(s)) })
+	for _, name := range names {
+		if !known(name) {
+			return nil, fmt.Errorf("unknown %s value %q (allowed: %s)", key, name, strings.Join(allowedNames(key), ", "))
+		}
+	}
+	return names, nil
+}
+
+// allowedNames lists the whitelist of a parameter, sorted.
+func allowedNames(key string) []string {
+	var names []string
+	if key == "fields" {
+		for name := range albumFields {
+			names = append(names, name)
+		}
+	} else {
+		for name := range albumIncludes {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether the response carries field.
+func (fs Fieldset) Has(field string) bool {
+	return fs.Fields == nil || slices.Contains(fs.Fields, field)
+}
+
+// Includes reports whether the related resource rel is embedded.
+func (fs Fieldset) Includes(rel string) bool {
+	return slices.Contains(fs.Include, rel)
+}
+
+// apply selects the columns the fieldset needs, plus the sort columns so
+// cursors can be built, and preloads the requested relations.
+func (fs Fieldset) apply(tx *gorm.DB, sort []SortField) *gorm.DB {
+	if fs.Fields != nil {
+		columns := slices.Clone(alwaysSelected)
+		for _, name := range fs.Fields {
+			columns = append(columns, albumFields[name].columns...)
+		}
+		for _, key := range sortKeys(sort) {
+			columns = append(columns, key.Column)
+		}
+		if fs.Includes("artist_detail") {
+			columns = append(columns, "artist_id")
+		}
+		slices.Sort(columns)
+		tx = tx.Select(slices.Compact(columns))
+	}
+
+	if fs.Has("genres") {
+		tx = tx.Preload("Genres", func(db *gorm.DB) *gorm.DB { return db.Order("genres.name ASC") })
+	}
+	if fs.Has("tags") {
+		tx = tx.Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("tags.name ASC") })
+	}
+	if fs.Includes("tracks") {
+		tx = tx.Preload("Tracks", orderTracks)
+	}
+	if fs.Includes("artist_detail") {
+		tx = tx.Preload("ArtistDetail")
+	}
+	return tx
+}
+
+// embeddedETag derives a weak entity tag, and the last modification time,
+// for an album representation in fs and mediaType that embeds related
+// resources, since edits to tracks or to the artist do not bump the album
+// version.
+func embeddedETag(album Album, fs Fieldset, mediaType string) (string, time.Time) {
+	h := sha256.New()
+	modified := album.UpdatedAt
+	fmt.Fprintf(h, "%d:%d|%s|%s|%s", album.ID, album.Version,
+		strings.Join(fs.Fields, ","), strings.Join(fs.Include, ","), mediaType)
+	for _, t := range album.Tracks {
+		fmt.Fprintf(h, "|%d:%d", t.ID, t.UpdatedAt.UnixNano())
+		if t.UpdatedAt.After(modified) {
+			modified = t.UpdatedAt
+		}
+	}
+	if a := album.ArtistDetail; a != nil {
+		fmt.Fprintf(h, "|artist %d:%d", a.ID, a.UpdatedAt.UnixNano())
+		if a.UpdatedAt.After(modified) {
+			modified = a.UpdatedAt
+		}
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`, modified
+}
+
+// albumView is an album response trimmed to a Fieldset.
+type albumView struct {
+	album  AlbumResponse
+	fields Fieldset
+}
+
+func (v albumView) MarshalJSON() ([]byte, error) {
+	if v.fields.Fields == nil {
+		return json.Marshal(v.album)
+	}
+	data, err := json.Marshal(v.album)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	out := make(map[string]json.RawMessage, len(v.fields.Fields)+len(v.fields.Include))
+	for _, name := range v.fields.Fields {
+		if raw, ok := all[name]; ok {
+			out[name] = raw
+		}
+	}
+	for _, rel := range v.fields.Include {
+		if raw, ok := all[rel]; ok {
+			out[rel] = raw
+		}
+	}
+	return json.Marshal(out)
+}
+
+// csvColumns are the indexes of the export columns the fieldset keeps.
+func (v albumView) csvColumns() []int {
+	var columns []int
+	for i, column := range exportColumns {
+		for name, f := range albumFields {
+			if f.csv == column && v.fields.Has(name) {
+				columns = append(columns, i)
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// CSVHeader and CSVRecord serve the export columns of the requested fields.
+func (v albumView) CSVHeader() []string {
+	var header []string
+	for _, i := range v.csvColumns() {
+		header = append(header, exportColumns[i])
+	}
+	return header
+}
+
+func (v albumView) CSVRecord() []string {
+	cells := v.album.CSVRecord()
+	var record []string
+	for _, i := range v.csvColumns() {
+		record = append(record, cells[i])
+	}
+	return record
+}
@@ -1,178 +1,899 @@
 package albums
 
 import (
+	"errors"
+	"gin-quickstart/internal/config"
+	"gin-quickstart/internal/dberr"
 	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/negotiate"
+	"gin-quickstart/internal/problem"
+	"io"
+	"log"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 // Handler holds the necessary dependencies for the album handlers.
 type Handler struct {
 	service Service
+	cfg     config.Config
+	// basePath is where the album routes are mounted, used to build cover URLs.
+	basePath string
 }
 
 // NewHandler is the constructor for Handler.
-func NewHandler(s Service) *Handler {
-	return &Handler{service: s}
+func NewHandler(s Service, cfg config.Config) *Handler {
+	return &Handler{service: s, cfg: cfg}
 }
 
 // RegisterRoutes attaches album routes to the Gin engine.
 func (h *Handler) RegisterRoutes(g *gin.RouterGroup) {
 	albumGroup := g.Group("/albums")
+	h.basePath = albumGroup.BasePath()
+
+	// Responses follow the Accept header; album and track lists can also be
+	// served as CSV. Downloads set their own media type.
+	rows := albumGroup.Group("", negotiate.Offer(negotiate.Tabular...))
+	docs := albumGroup.Group("", negotiate.Offer(negotiate.Structured...))
 	{
 		// 1. READ routes (accessible to anyone with a valid token: 'user' or 'admin')
-		albumGroup.GET("/", h.GetAlbums)
-		albumGroup.GET("/:id", h.GetAlbumByID)
+		rows.GET("/", middleware.CacheControl(h.cfg.Cache.AlbumList), h.GetAlbums)
+		rows.GET("/search", h.SearchAlbums)
+		docs.GET("/facets", middleware.CacheControl(h.cfg.Cache.AlbumList), h.GetAlbumFacets)
+		albumGroup.GET("/export", h.ExportAlbums)
+		rows.GET("/:id", middleware.CacheControl(h.cfg.Cache.AlbumDetail), h.GetAlbumByID)
+		docs.GET("/:id/revisions", h.GetAlbumRevisions)
+		rows.GET("/:id/tracks", h.GetTracks)
+		albumGroup.GET("/:id/cover/:rendition", middleware.CacheControl(h.cfg.Cache.Cover), h.GetCover)
 
 		// 2. WRITE routes (only accessible to 'admin')
-		adminAlbumGroup := albumGroup.Group("/")
+		adminAlbumGroup := docs.Group("/")
 		adminAlbumGroup.Use(middleware.Authorize("admin"))
 		{
 			adminAlbumGroup.POST("/", h.CreateAlbum)
+			adminAlbumGroup.POST("/import", h.ImportAlbums)
 			adminAlbumGroup.PUT("/:id", h.UpdateAlbum)
+			adminAlbumGroup.PATCH("/:id", h.PatchAlbum)
 			adminAlbumGroup.DELETE("/:id", h.DeleteAlbum)
+
+			// Trash bin for soft-deleted albums
+			rows.GET("/trash", middleware.Authorize("admin"), h.GetTrashedAlbums)
+			adminAlbumGroup.POST("/:id/restore", h.RestoreAlbum)
+
+			// Revision history
+			adminAlbumGroup.POST("/:id/revisions/:rev/restore", h.RollbackAlbum)
+
+			// Track listing
+			adminAlbumGroup.POST("/:id/tracks", h.CreateTrack)
+			adminAlbumGroup.PUT("/:id/tracks/order", h.ReorderTracks)
+			adminAlbumGroup.PUT("/:id/tracks/:trackId", h.UpdateTrack)
+			adminAlbumGroup.DELETE("/:id/tracks/:trackId", h.DeleteTrack)
+
+			// Classification
+			adminAlbumGroup.PUT("/:id/genres", h.SetAlbumGenres)
+			adminAlbumGroup.PUT("/:id/tags", h.SetAlbumTags)
+
+			// Cover art
+			adminAlbumGroup.POST("/:id/cover", h.UploadCover)
 		}
 	}
+
+	// Albums credited to an artist
+	g.GET("/artists/:id/albums", negotiate.Offer(negotiate.Tabular...), middleware.CacheControl(h.cfg.Cache.AlbumList), h.GetArtistAlbums)
 }
 
-// GetAlbums retrieves all albums via the service and returns a 200 OK response.
+// GetAlbums retrieves a filtered, sorted page of albums and returns a 200 OK response.
 func (h *Handler) GetAlbums(c *gin.Context) {
-	// Call service to get all albums
-	albums, err := h.service.FindAll()
+	// 1. Parse filters, sort and pagination from the query string
+	opts, err := ParseQueryOptions(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 2. Keyset mode: walk the listing with signed cursors
+	if opts.CursorMode {
+		albums, next, err := h.service.FindAllAfter(opts)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if notModified(c, listETag(albums, -1, next, opts.Fieldset, negotiate.MediaType(c)), lastModified(albums...)) {
+			return
+		}
+		negotiate.Render(c, http.StatusOK, gin.H{
+			"data": gin.H{
+				"albums": h.presentAll(albums, opts.Fieldset),
+			},
+			"meta":    buildCursorMeta(c.Request.URL, opts, next),
+			"message": "Albums retrieved successfully",
+		})
+		return
+	}
+
+	// 3. Call service to get the requested page
+	albums, total, err := h.service.FindAll(opts)
 	if err != nil {
 		// Handle error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
-	// Return albums with 200 OK status
-	c.JSON(http.StatusOK, gin.H{
+
+	// 4. Answer 304 if the client's cached copy of this page is current
+	if notModified(c, listETag(albums, total, "", opts.Fieldset, negotiate.MediaType(c)), lastModified(albums...)) {
+		return
+	}
+
+	// 5. Return albums with pagination meta and 200 OK status
+	negotiate.Render(c, http.StatusOK, gin.H{
 		"data": gin.H{
-			"albums": albums,
+			"albums": h.presentAll(albums, opts.Fieldset),
 		},
+		"meta":    buildListMeta(c.Request.URL, opts.Limit, opts.Offset, total),
 		"message": "Albums retrieved successfully",
 	})
 }
 
+// SearchAlbums searches album titles and artists in exact, full-text or fuzzy mode.
+func (h *Handler) SearchAlbums(c *gin.Context) {
+	// 1. Parse the search terms and pagination
+	opts, err := ParseSearchOptions(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 2. Call service to run the search
+	page, err := h.service.Search(opts)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 3. Return ranked results with suggestions and pagination meta
+	results := make([]SearchResultResponse, len(page.Results))
+	for i, r := range page.Results {
+		results[i] = NewSearchResultResponse(r)
+		results[i].Cover = coverURLs(h.basePath, r.Album, h.cfg.Covers.ThumbnailSizes)
+	}
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"results":     results,
+			"suggestions": page.Suggestions,
+			"mode":        opts.Mode,
+		},
+		"meta":    buildListMeta(c.Request.URL, opts.Limit, opts.Offset, page.Total),
+		"message": "Search completed successfully",
+	})
+}
+
+// GetAlbumFacets counts the albums matching the listing filters per genre and
+// per tag, for rendering filter sidebars.
+func (h *Handler) GetAlbumFacets(c *gin.Context) {
+	// 1. Parse the same filters as the listing
+	opts, err := ParseQueryOptions(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 2. Call service to count albums per genre and tag
+	facets, err := h.service.Facets(opts)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 3. Return the facet counts
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"facets": facets,
+		},
+		"message": "Album facets retrieved successfully",
+	})
+}
+
+// ExportAlbums streams every album matching the listing filters as a CSV,
+// NDJSON or XLSX download. Rows are written as they are read from the
+// database, so the response is sent with 200 before the export finishes;
+// an error part-way through is logged and the connection is cut short.
+func (h *Handler) ExportAlbums(c *gin.Context) {
+	// 1. Parse the format and the same filters as the listing
+	format, err := ParseExportFormat(c.Query("format"))
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+	opts, err := ParseQueryOptions(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 2. Start the download once the first batch has been read
+	var out ExportWriter
+	start := func() error {
+		filename := "albums-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+		c.Header("Content-Type", ExportContentType(format))
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		c.Header("Cache-Control", "no-store")
+		c.Status(http.StatusOK)
+		out, err = NewExportWriter(format, c.Writer)
+		return err
+	}
+
+	// 3. Call service to stream the albums, flushing after every batch
+	err = h.service.Export(opts, func(batch []Album) error {
+		if out == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := out.Write(batch); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err == nil && out == nil {
+		// Nothing matched: send a file holding only the header
+		err = start()
+	}
+	if err != nil {
+		if out == nil && !c.Writer.Written() {
+			c.Error(err)
+			return
+		}
+		abortStream(c, err)
+		return
+	}
+
+	// 4. Complete the file
+	if err := out.Close(); err != nil {
+		abortStream(c, err)
+	}
+}
+
+// abortStream ends a download that failed after the response was started.
+// Closing the connection without finishing the chunked body lets clients
+// tell the file is incomplete instead of saving a truncated copy.
+func abortStream(c *gin.Context, err error) {
+	log.Printf("albums: export failed: %v", err)
+	c.Abort()
+	if conn, _, hijackErr := c.Writer.Hijack(); hijackErr == nil {
+		conn.Close()
+	}
+}
+
 // CreateAlbum processes a POST request to add a new album.
 func (h *Handler) CreateAlbum(c *gin.Context) {
-	var album Album
+	var req AlbumRequest
 
-	// Bind JSON body to album struct
-	if err := c.BindJSON(&album); err != nil {
-		// Handle JSON binding error
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Bind the request body to the album request
+	if err := negotiate.Bind(c, &req); err != nil {
+		// Handle binding error
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
 		return
 	}
 
 	// Call service to create album
-	created, err := h.service.Create(album)
+	created, err := h.service.Create(req.toAlbum(), actorID(c))
 	if err != nil {
 		//	 Handle creation error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondAlbumError(c, err)
 		return
 	}
 
 	// Return created album with 201 Created status
-	c.JSON(http.StatusCreated, gin.H{
+	negotiate.Render(c, http.StatusCreated, gin.H{
 		"data": gin.H{
-			"album": created,
+			"album": h.toResponse(created),
 		},
 		"message": "Album created successfully",
 	})
 }
 
+// ImportAlbums bulk-creates albums from a CSV file or a JSON array, reading
+// the body as a stream.
+func (h *Handler) ImportAlbums(c *gin.Context) {
+	// 1. Parse dry_run, atomic and on_duplicate
+	opts, err := ParseImportOptions(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 2. Pick the reader for the body's media type
+	src, err := NewImportSource(c.ContentType(), c.Request.Body)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedImport) {
+			c.Error(problem.New(http.StatusUnsupportedMediaType, "Content-Type must be "+CSVContentType+" or "+JSONContentType))
+		} else {
+			c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		}
+		return
+	}
+
+	// 3. Call service to run the import
+	report, err := h.service.Import(src, opts, actorID(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 4. Return the report; a rolled back atomic import is unprocessable
+	status, message := http.StatusOK, "Albums imported successfully"
+	switch {
+	case opts.DryRun:
+		message = "Dry run completed, nothing was saved"
+	case !report.Committed:
+		status, message = http.StatusUnprocessableEntity, "Import rolled back, no albums were saved"
+	case report.Failed > 0:
+		message = "Albums imported with errors"
+	}
+	negotiate.Render(c, status, gin.H{
+		"data": gin.H{
+			"report": report,
+		},
+		"message": message,
+	})
+}
+
 // GetAlbumByID retrieves a single album by its ID.
 func (h *Handler) GetAlbumByID(c *gin.Context) {
 	idStr := c.Param("id")
 	// 1. Convert string URL param to uint
 	idUint, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format. Must be an integer."})
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Parse the sparse fieldset (?fields=, ?include=)
+	fs, err := ParseFieldset(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
 		return
 	}
 
-	// 2. Call service to find by ID
-	album, err := h.service.FindById(uint(idUint))
+	// 3. Call service to find by ID with the requested fields and relations
+	album, err := h.service.FindByIdFields(uint(idUint), fs)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, dberr.ErrNotFound) {
 			// Handle not found error
-			c.JSON(http.StatusNotFound, gin.H{"error": "album not found"})
+			c.Error(problem.New(http.StatusNotFound, "album not found"))
 		} else {
 			// Handle other errors
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.Error(err)
 		}
 		return
 	}
 
-	// 3. Return found album
-	c.JSON(http.StatusOK, gin.H{
-		"data": gin.H{
-			"album": album,
-		},
+	// 4. Answer 304 if the client's cached copy is current; every fieldset
+	// and media type is a representation with its own tag
+	mediaType := negotiate.MediaType(c)
+	etag, modified := variantETag(album.Version, fs, mediaType), album.UpdatedAt
+	if len(fs.Include) > 0 {
+		etag, modified = embeddedETag(album, fs, mediaType)
+	}
+	if notModified(c, etag, modified) {
+		return
+	}
+
+	// 5. Return found album, with total runtime when tracks are embedded
+	data := gin.H{"album": h.present(album, fs)}
+	if fs.Includes("tracks") {
+		data["total_runtime"] = TotalRuntime(album.Tracks)
+	}
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data":    data,
 		"message": "Album retrieved successfully",
 	})
 }
 
 // UpdateAlbum processes a PUT request to update an existing album.
 func (h *Handler) UpdateAlbum(c *gin.Context) {
-	var album Album
+	var req AlbumRequest
 	idStr := c.Param("id")
 
 	// 1. Convert string URL param to uint
 	idUint, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format. Must be an integer."})
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
 		return
 	}
 
-	// 2. Bind JSON body to album struct
-	if err := c.BindJSON(&album); err != nil {
-		// Handle JSON binding error
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// 2. Bind the request body to the album request
+	if err := negotiate.Bind(c, &req); err != nil {
+		// Handle binding error
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
 		return
 	}
 
 	// 3. Set the ID from the URL param
+	album := req.toAlbum()
 	album.ID = uint(idUint)
 
-	// 4. Call service to update
-	updated, err := h.service.Update(album)
+	// 4. Call service to update, guarded by If-Match
+	updated, err := h.service.Update(album, ParseIfMatch(c.GetHeader("If-Match")), actorID(c))
 	if err != nil {
-		// Handle not found error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondAlbumError(c, err)
 		return
 	}
 
-	// 5. Return updated album
-	c.JSON(http.StatusOK, gin.H{
+	// 5. Return updated album with its new ETag
+	c.Header("ETag", variantETag(updated.Version, Fieldset{}, negotiate.MediaType(c)))
+	negotiate.Render(c, http.StatusOK, gin.H{
 		"data": gin.H{
-			"album": updated,
+			"album": h.toResponse(updated),
 		},
 		"message": "Album updated successfully",
 	})
 }
 
-// delete album by ID
+// PatchAlbum processes a PATCH request carrying either a JSON Merge Patch
+// (application/merge-patch+json) or a JSON Patch (application/json-patch+json).
+func (h *Handler) PatchAlbum(c *gin.Context) {
+	idStr := c.Param("id")
+
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Read the raw patch document, up to the request body limit
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, negotiate.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(problem.New(http.StatusRequestEntityTooLarge, negotiate.ErrBodyTooLarge.Error()))
+		} else {
+			c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		}
+		return
+	}
+
+	// 3. Call service to apply the patch
+	updated, err := h.service.Patch(uint(idUint), patch, c.ContentType(), ParseIfMatch(c.GetHeader("If-Match")), actorID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnsupportedPatch):
+			c.Error(problem.New(http.StatusUnsupportedMediaType, "Content-Type must be "+MergePatchContentType+" or "+JSONPatchContentType))
+		default:
+			respondAlbumError(c, err)
+		}
+		return
+	}
+
+	// 4. Return patched album with its new ETag
+	c.Header("ETag", variantETag(updated.Version, Fieldset{}, negotiate.MediaType(c)))
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"album": h.toResponse(updated),
+		},
+		"message": "Album updated successfully",
+	})
+}
+
+// DeleteAlbum moves an album to the trash, or removes it permanently with ?hard=true.
 func (h *Handler) DeleteAlbum(c *gin.Context) {
 	idStr := c.Param("id")
 
 	// 1. Convert string URL param to uint
 	idUint, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID format. Must be an integer."})
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
 		return
 	}
 
-	// 2. Call service to delete
-	if err := h.service.Delete(uint(idUint)); err != nil {
-		// Handle not found error
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// 2. Decide between soft and hard delete
+	hard := false
+	if raw := c.Query("hard"); raw != "" {
+		if hard, err = strconv.ParseBool(raw); err != nil {
+			c.Error(problem.New(http.StatusBadRequest, "hard must be a boolean"))
+			return
+		}
+	}
+
+	// 3. Call service to delete, guarded by If-Match
+	cond := ParseIfMatch(c.GetHeader("If-Match"))
+	if hard {
+		err = h.service.Purge(uint(idUint), cond, actorID(c))
+	} else {
+		err = h.service.Delete(uint(idUint), cond, actorID(c))
+	}
+	if err != nil {
+		respondAlbumError(c, err)
 		return
 	}
 
-	// 3. Return no content status
+	// 4. Return no content status
 	c.Status(http.StatusNoContent)
 }
+
+// GetTrashedAlbums lists soft-deleted albums, most recently deleted first.
+func (h *Handler) GetTrashedAlbums(c *gin.Context) {
+	// 1. Parse pagination
+	limit, offset, err := parsePagination(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 2. Call service to list the trash
+	albums, total, err := h.service.FindTrashed(limit, offset)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 3. Return trashed albums with pagination meta
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"albums": h.toResponses(albums),
+		},
+		"meta":    buildListMeta(c.Request.URL, limit, offset, total),
+		"message": "Trashed albums retrieved successfully",
+	})
+}
+
+// RestoreAlbum takes a soft-deleted album out of the trash.
+func (h *Handler) RestoreAlbum(c *gin.Context) {
+	idStr := c.Param("id")
+
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Call service to restore
+	album, err := h.service.Restore(uint(idUint), actorID(c))
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	// 3. Return restored album with its new ETag
+	c.Header("ETag", variantETag(album.Version, Fieldset{}, negotiate.MediaType(c)))
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"album": h.toResponse(album),
+		},
+		"message": "Album restored successfully",
+	})
+}
+
+// GetAlbumRevisions lists an album's change history with field-level diffs.
+func (h *Handler) GetAlbumRevisions(c *gin.Context) {
+	idStr := c.Param("id")
+
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Parse pagination
+	limit, offset, err := parsePagination(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 3. Call service to load the history
+	revisions, total, err := h.service.FindRevisions(uint(idUint), limit, offset)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 4. Return revisions with pagination meta
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"revisions": NewRevisionResponses(revisions),
+		},
+		"meta":    buildListMeta(c.Request.URL, limit, offset, total),
+		"message": "Album revisions retrieved successfully",
+	})
+}
+
+// RollbackAlbum restores an album to the state recorded in one of its revisions.
+func (h *Handler) RollbackAlbum(c *gin.Context) {
+	// 1. Convert string URL params to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+	revUint, err := strconv.ParseUint(c.Param("rev"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid revision format. Must be an integer."))
+		return
+	}
+
+	// 2. Call service to roll back, guarded by If-Match
+	album, err := h.service.Rollback(uint(idUint), uint(revUint), ParseIfMatch(c.GetHeader("If-Match")), actorID(c))
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	// 3. Return rolled back album with its new ETag
+	c.Header("ETag", variantETag(album.Version, Fieldset{}, negotiate.MediaType(c)))
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"album": h.toResponse(album),
+		},
+		"message": "Album rolled back successfully",
+	})
+}
+
+// SetAlbumGenres replaces an album's genres with the given genre slugs.
+func (h *Handler) SetAlbumGenres(c *gin.Context) {
+	var body GenreAssignment
+
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Bind the request body
+	if err := negotiate.Bind(c, &body); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
+		return
+	}
+
+	// 3. Call service to assign the genres, guarded by If-Match
+	album, err := h.service.SetGenres(uint(idUint), body.Genres, ParseIfMatch(c.GetHeader("If-Match")), actorID(c))
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	// 4. Return updated album with its new ETag
+	c.Header("ETag", variantETag(album.Version, Fieldset{}, negotiate.MediaType(c)))
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"album": h.toResponse(album),
+		},
+		"message": "Album genres updated successfully",
+	})
+}
+
+// SetAlbumTags replaces an album's free-form tags.
+func (h *Handler) SetAlbumTags(c *gin.Context) {
+	var body TagAssignment
+
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Bind the request body
+	if err := negotiate.Bind(c, &body); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
+		return
+	}
+
+	// 3. Call service to assign the tags, guarded by If-Match
+	album, err := h.service.SetTags(uint(idUint), body.Tags, ParseIfMatch(c.GetHeader("If-Match")), actorID(c))
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	// 4. Return updated album with its new ETag
+	c.Header("ETag", variantETag(album.Version, Fieldset{}, negotiate.MediaType(c)))
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"album": h.toResponse(album),
+		},
+		"message": "Album tags updated successfully",
+	})
+}
+
+// GetArtistAlbums lists the albums credited to an artist, with the same
+// filters, sorting and pagination as GetAlbums.
+func (h *Handler) GetArtistAlbums(c *gin.Context) {
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Parse filters, sort and pagination from the query string
+	opts, err := ParseQueryOptions(c.Request.URL.Query())
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 3. Call service to get the artist's albums
+	albums, total, err := h.service.FindByArtist(uint(idUint), opts)
+	if err != nil {
+		if errors.Is(err, dberr.ErrNotFound) {
+			c.Error(problem.New(http.StatusNotFound, "artist not found"))
+		} else {
+			c.Error(err)
+		}
+		return
+	}
+
+	// 4. Answer 304 if the client's cached copy of this page is current
+	if notModified(c, listETag(albums, total, "", opts.Fieldset, negotiate.MediaType(c)), lastModified(albums...)) {
+		return
+	}
+
+	// 5. Return albums with pagination meta
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"albums": h.presentAll(albums, opts.Fieldset),
+		},
+		"meta":    buildListMeta(c.Request.URL, opts.Limit, opts.Offset, total),
+		"message": "Artist albums retrieved successfully",
+	})
+}
+
+// UploadCover accepts a multipart/form-data upload of the album's cover image
+// in the "cover" field.
+func (h *Handler) UploadCover(c *gin.Context) {
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Read the image from the multipart form, leaving room for the form overhead
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.Covers.MaxBytes+1<<20)
+	header, err := c.FormFile(CoverFormField)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(ErrCoverTooLarge)
+		} else {
+			c.Error(problem.New(http.StatusBadRequest, "multipart field \""+CoverFormField+"\" is required"))
+		}
+		return
+	}
+	if header.Size > h.cfg.Covers.MaxBytes {
+		c.Error(ErrCoverTooLarge)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer file.Close()
+
+	// 3. Call service to store the cover, guarded by If-Match
+	album, err := h.service.UploadCover(uint(idUint), file, ParseIfMatch(c.GetHeader("If-Match")), actorID(c))
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+
+	// 4. Return updated album with its cover URLs and new ETag
+	c.Header("ETag", variantETag(album.Version, Fieldset{}, negotiate.MediaType(c)))
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"album": h.toResponse(album),
+		},
+		"message": "Album cover uploaded successfully",
+	})
+}
+
+// GetCover serves the original cover image or one of its thumbnails. Cover
+// URLs carry the image's content hash, so responses can be cached for long.
+func (h *Handler) GetCover(c *gin.Context) {
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Call service to open the requested rendition
+	blob, err := h.service.OpenCover(uint(idUint), c.Param("rendition"))
+	if err != nil {
+		respondAlbumError(c, err)
+		return
+	}
+	defer blob.Body.Close()
+
+	// 3. Answer 304 if the client's cached copy is current
+	if notModified(c, blob.ETag, blob.ModTime) {
+		return
+	}
+
+	// 4. Stream the image
+	c.DataFromReader(http.StatusOK, blob.Size, blob.ContentType, blob.Body, map[string]string{
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// toResponse maps album to its response, with cover URLs.
+func (h *Handler) toResponse(album Album) AlbumResponse {
+	resp := NewAlbumResponse(album)
+	resp.Cover = coverURLs(h.basePath, album, h.cfg.Covers.ThumbnailSizes)
+	return resp
+}
+
+// toResponses applies toResponse to every album.
+func (h *Handler) toResponses(albums []Album) []AlbumResponse {
+	resps := make([]AlbumResponse, len(albums))
+	for i, album := range albums {
+		resps[i] = h.toResponse(album)
+	}
+	return resps
+}
+
+// present maps album to its response trimmed to the fieldset, with cover
+// URLs if requested.
+func (h *Handler) present(album Album, fs Fieldset) albumView {
+	resp := NewAlbumResponse(album)
+	if fs.Has("cover") {
+		resp.Cover = coverURLs(h.basePath, album, h.cfg.Covers.ThumbnailSizes)
+	}
+	return albumView{album: resp, fields: fs}
+}
+
+// presentAll applies present to every album.
+func (h *Handler) presentAll(albums []Album, fs Fieldset) []albumView {
+	views := make([]albumView, len(albums))
+	for i, album := range albums {
+		views[i] = h.present(album, fs)
+	}
+	return views
+}
+
+// actorID returns the ID of the authenticated user making the request.
+func actorID(c *gin.Context) uint {
+	if claims, ok := middleware.CurrentClaims(c); ok {
+		return claims.ID
+	}
+	return 0
+}
+
+// ErrorStatuses maps the album domain errors to HTTP statuses for
+// middleware.Problems.
+var ErrorStatuses = []problem.Mapping{
+	{Err: ErrPreconditionRequired, Status: http.StatusPreconditionRequired},
+	{Err: ErrVersionMismatch, Status: http.StatusPreconditionFailed},
+	{Err: ErrInvalidAlbum, Status: http.StatusUnprocessableEntity},
+	{Err: ErrInvalidPatch, Status: http.StatusBadRequest},
+	{Err: ErrInvalidCursor, Status: http.StatusBadRequest},
+	{Err: ErrInvalidImport, Status: http.StatusBadRequest},
+	{Err: ErrNotInTrash, Status: http.StatusConflict},
+	{Err: ErrRevisionNotRestorable, Status: http.StatusUnprocessableEntity},
+	{Err: ErrInvalidTrack, Status: http.StatusUnprocessableEntity},
+	{Err: ErrInvalidTrackOrder, Status: http.StatusUnprocessableEntity},
+	{Err: ErrCoverTooLarge, Status: http.StatusRequestEntityTooLarge},
+	{Err: ErrUnsupportedCover, Status: http.StatusUnsupportedMediaType},
+	{Err: ErrInvalidCover, Status: http.StatusUnprocessableEntity},
+	{Err: ErrNoCover, Status: http.StatusNotFound},
+}
+
+// respondAlbumError reports a failed album request; domain errors are
+// mapped by ErrorStatuses, and a missing record is named as the album.
+func respondAlbumError(c *gin.Context, err error) {
+	if errors.Is(err, dberr.ErrNotFound) {
+		err = problem.New(http.StatusNotFound, "album not found")
+	}
+	c.Error(err)
+}
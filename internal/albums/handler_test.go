@@ -0,0 +1,151 @@
+package albums
+
+import (
+	"gin-quickstart/internal/artists"
+	"gin-quickstart/internal/config"
+	"gin-quickstart/internal/dberr"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/negotiate"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// albumRepo is a Repository holding a single album. Methods the tests do
+// not reach are left to the nil embedded interface.
+type albumRepo struct {
+	Repository
+	album Album
+}
+
+func (r *albumRepo) Transaction(fn func(repo Repository) error) error {
+	return fn(r)
+}
+
+func (r *albumRepo) FindById(id uint) (Album, error) {
+	if id != r.album.ID {
+		return Album{}, dberr.ErrNotFound
+	}
+	return r.album, nil
+}
+
+func (r *albumRepo) UpdateFields(id uint, versions []uint, fields map[string]any) (Album, error) {
+	if _, err := r.FindById(id); err != nil {
+		return Album{}, err
+	}
+	if len(versions) > 0 && !slices.Contains(versions, r.album.Version) {
+		return Album{}, ErrVersionMismatch
+	}
+	if title, ok := fields["title"].(string); ok {
+		r.album.Title = title
+	}
+	r.album.Version++
+	return r.album, nil
+}
+
+func (r *albumRepo) CreateRevision(Revision) error {
+	return nil
+}
+
+// artistResolver resolves every artist ID to the same artist.
+type artistResolver struct{}
+
+func (artistResolver) FindById(id uint) (artists.Artist, error) {
+	artist := artists.Artist{Name: "John Coltrane"}
+	artist.ID = id
+	return artist, nil
+}
+
+func (artistResolver) FindOrCreateByName(name string) (artists.Artist, error) {
+	return artistResolver{}.FindById(1)
+}
+
+func TestUpdateAlbumPreconditions(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		ifMatch        string
+		requireIfMatch bool
+		status         int
+		etag           string
+	}{
+		{"missing If-Match when required", "/albums/1", "", true, http.StatusPreconditionRequired, ""},
+		{"missing If-Match when optional", "/albums/1", "", false, http.StatusOK, `"4"`},
+		{"current version", "/albums/1", `"3"`, true, http.StatusOK, `"4"`},
+		{"current version among others", "/albums/1", `"1", "3"`, true, http.StatusOK, `"4"`},
+		{"tag of another representation", "/albums/1", `"3-0011aabb"`, true, http.StatusOK, `"4"`},
+		{"wildcard", "/albums/1", `*`, true, http.StatusOK, `"4"`},
+		{"stale version", "/albums/1", `"2"`, true, http.StatusPreconditionFailed, ""},
+		{"weak tag only", "/albums/1", `W/"3"`, true, http.StatusPreconditionFailed, ""},
+		{"malformed tag", "/albums/1", `3`, true, http.StatusPreconditionFailed, ""},
+		{"unknown album", "/albums/9", `"3"`, true, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			album := Album{Title: "Blue Train", Artist: "John Coltrane", Version: 3}
+			album.ID = 1
+			var cfg config.Config
+			cfg.Albums.RequireIfMatch = tt.requireIfMatch
+			h := NewHandler(NewService(&albumRepo{album: album}, artistResolver{}, nil, nil, cfg), cfg)
+
+			router := gin.New()
+			router.Use(middleware.Problems(ErrorStatuses))
+			router.PUT("/albums/:id", h.UpdateAlbum)
+
+			req := httptest.NewRequest(http.MethodPut, tt.path, strings.NewReader(`{"title":"Giant Steps","artist_id":1}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if got := w.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("ETag = %q, want %q", got, tt.etag)
+			}
+		})
+	}
+}
+
+func TestPatchAlbumBodyLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"within limit", `{"title":"Giant Steps"}`, http.StatusOK},
+		{"over limit", `{"title":"` + strings.Repeat("a", negotiate.MaxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			album := Album{Title: "Blue Train", Artist: "John Coltrane", Version: 3}
+			album.ID = 1
+			var cfg config.Config
+			h := NewHandler(NewService(&albumRepo{album: album}, artistResolver{}, nil, nil, cfg), cfg)
+
+			router := gin.New()
+			router.Use(middleware.Problems(ErrorStatuses))
+			router.PATCH("/albums/:id", h.PatchAlbum)
+
+			req := httptest.NewRequest(http.MethodPatch, "/albums/1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", MergePatchContentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %.200s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
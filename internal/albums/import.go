@@ -0,0 +1,361 @@
+package albums
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Media types accepted by POST /albums/import.
+const (
+	CSVContentType  = "text/csv"
+	JSONContentType = "application/json"
+)
+
+// ImportBatchSize is the number of rows committed per transaction when an
+// import is not atomic.
+const ImportBatchSize = 500
+
+// MaxImportErrors caps the number of row errors listed in an import report.
+const MaxImportErrors = 1000
+
+// Duplicate handling policies for imports. A row duplicates an album when
+// both have the same artist and the same title, ignoring case.
+const (
+	OnDuplicateFail   = "fail"
+	OnDuplicateSkip   = "skip"
+	OnDuplicateUpdate = "update"
+)
+
+// csvListSeparator separates genres and tags within a CSV cell.
+const csvListSeparator = "|"
+
+var (
+	// ErrUnsupportedImport is returned for an import body in an unknown media type.
+	ErrUnsupportedImport = errors.New("unsupported import content type")
+	// ErrInvalidImport is returned when an import file cannot be read at all.
+	ErrInvalidImport = errors.New("invalid import file")
+)
+
+// ImportOptions controls how an import is applied.
+type ImportOptions struct {
+	// DryRun validates every row and reports what would happen, then rolls back.
+	DryRun bool
+	// Atomic runs the import in a single transaction that is rolled back if
+	// any row fails. Otherwise rows are committed in batches of
+	// ImportBatchSize and failed rows are skipped.
+	Atomic      bool
+	OnDuplicate string
+}
+
+// ImportError describes a row that could not be imported.
+type ImportError struct {
+	Line    int    `json:"line"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+
+	// fatal marks errors after which the rest of the file cannot be read.
+	fatal bool
+}
+
+func (e *ImportError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ImportReport summarises an import. In a dry run, or when an atomic import
+// is rolled back, the counts describe what would have happened.
+type ImportReport struct {
+	DryRun    bool          `json:"dry_run"`
+	Atomic    bool          `json:"atomic"`
+	Committed bool          `json:"committed"`
+	Aborted   bool          `json:"aborted"`
+	Rows      int           `json:"rows"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Skipped   int           `json:"skipped"`
+	Failed    int           `json:"failed"`
+	Errors    []ImportError `json:"errors"`
+	// ErrorsTruncated is set when more than MaxImportErrors rows failed.
+	ErrorsTruncated bool `json:"errors_truncated,omitempty"`
+}
+
+// addError records a failed row.
+func (r *ImportReport) addError(e ImportError) {
+	r.Failed++
+	if e.fatal {
+		r.Aborted = true
+	}
+	if len(r.Errors) < MaxImportErrors {
+		r.Errors = append(r.Errors, e)
+	} else {
+		r.ErrorsTruncated = true
+	}
+}
+
+// importTally counts the outcome of the rows in one batch.
+type importTally struct {
+	created, updated, skipped int
+}
+
+// ImportRow is one album read from an import file.
+type ImportRow struct {
+	Line     int
+	Title    string
+	Artist   string
+	ArtistID *uint
+	Genres   []string
+	Tags     []string
+}
+
+// ImportSource yields the rows of an import file one at a time. Next returns
+// io.EOF after the last row and an *ImportError for a row that cannot be
+// read; any other error aborts the import.
+type ImportSource interface {
+	Next() (ImportRow, error)
+}
+
+// ParseImportOptions builds ImportOptions from the request's query string.
+func ParseImportOptions(q url.Values) (ImportOptions, error) {
+	opts := ImportOptions{Atomic: true, OnDuplicate: OnDuplicateFail}
+
+	var err error
+	if raw := q.Get("dry_run"); raw != "" {
+		if opts.DryRun, err = strconv.ParseBool(raw); err != nil {
+			return ImportOptions{}, fmt.Errorf("dry_run must be a boolean")
+		}
+	}
+	if raw := q.Get("atomic"); raw != "" {
+		if opts.Atomic, err = strconv.ParseBool(raw); err != nil {
+			return ImportOptions{}, fmt.Errorf("atomic must be a boolean")
+		}
+	}
+	switch raw := strings.ToLower(q.Get("on_duplicate")); raw {
+	case "":
+	case OnDuplicateFail, OnDuplicateSkip, OnDuplicateUpdate:
+		opts.OnDuplicate = raw
+	default:
+		return ImportOptions{}, fmt.Errorf("on_duplicate must be %q, %q or %q", OnDuplicateFail, OnDuplicateSkip, OnDuplicateUpdate)
+	}
+	return opts, nil
+}
+
+// NewImportSource returns the ImportSource for body in the given media type.
+func NewImportSource(contentType string, body io.Reader) (ImportSource, error) {
+	switch mediaType(contentType) {
+	case CSVContentType:
+		return newCSVSource(body)
+	case JSONContentType:
+		return newJSONSource(body)
+	default:
+		return nil, ErrUnsupportedImport
+	}
+}
+
+// csvSource reads albums from CSV with a header row naming the columns:
+// title, artist, artist_id, genres and tags, in any order. Genres and tags
+// hold several values separated by "|".
+type csvSource struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+// csvColumns are the recognised CSV header names.
+var csvColumns = map[string]bool{"title": true, "artist": true, "artist_id": true, "genres": true, "tags": true}
+
+func newCSVSource(body io.Reader) (*csvSource, error) {
+	r := csv.NewReader(body)
+	r.TrimLeadingSpace = true
+	r.ReuseRecord = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !csvColumns[name] {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidImport, name)
+		}
+		if _, dup := columns[name]; dup {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidImport, name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("%w: missing title column", ErrInvalidImport)
+	}
+	return &csvSource{r: r, columns: columns}, nil
+}
+
+func (s *csvSource) Next() (ImportRow, error) {
+	record, err := s.r.Read()
+	if err == io.EOF {
+		return ImportRow{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return ImportRow{}, &ImportError{
+			Line:    parseErr.StartLine,
+			Message: parseErr.Err.Error(),
+			// A wrong field count leaves the reader in step; anything else does not.
+			fatal: !errors.Is(parseErr.Err, csv.ErrFieldCount),
+		}
+	}
+	if err != nil {
+		return ImportRow{}, err
+	}
+
+	line, _ := s.r.FieldPos(0)
+	row := ImportRow{
+		Line:   line,
+		Title:  s.cell(record, "title"),
+		Artist: s.cell(record, "artist"),
+		Genres: splitList(s.cell(record, "genres")),
+		Tags:   splitList(s.cell(record, "tags")),
+	}
+	if raw := s.cell(record, "artist_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return ImportRow{}, &ImportError{Line: line, Field: "artist_id", Message: "must be an integer"}
+		}
+		artistID := uint(id)
+		row.ArtistID = &artistID
+	}
+	return row, nil
+}
+
+// cell returns the trimmed value of the named column, or "" if it is absent.
+func (s *csvSource) cell(record []string, column string) string {
+	i, ok := s.columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// splitList splits a "|"-separated CSV cell, dropping empty entries.
+func splitList(cell string) []string {
+	var values []string
+	for _, v := range strings.Split(cell, csvListSeparator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// jsonRecord is one element of a JSON import array.
+type jsonRecord struct {
+	Title    string   `json:"title"`
+	Artist   string   `json:"artist"`
+	ArtistID *uint    `json:"artist_id"`
+	Genres   []string `json:"genres"`
+	Tags     []string `json:"tags"`
+}
+
+// jsonSource reads albums from a JSON array, decoding one element at a time.
+type jsonSource struct {
+	dec   *json.Decoder
+	lines *lineCounter
+	done  bool
+}
+
+func newJSONSource(body io.Reader) (*jsonSource, error) {
+	lines := &lineCounter{r: body}
+	dec := json.NewDecoder(lines)
+	tok, err := dec.Token()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty body", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("%w: expected a JSON array of albums", ErrInvalidImport)
+	}
+	return &jsonSource{dec: dec, lines: lines}, nil
+}
+
+func (s *jsonSource) Next() (ImportRow, error) {
+	if s.done || !s.dec.More() {
+		s.done = true
+		return ImportRow{}, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		s.done = true
+		// Syntax error offsets are relative to the start of the element.
+		var syntaxErr *json.SyntaxError
+		switch {
+		case errors.As(err, &syntaxErr):
+			line := s.lines.lineAt(s.dec.InputOffset() + syntaxErr.Offset - 1)
+			return ImportRow{}, &ImportError{Line: line, Message: err.Error(), fatal: true}
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return ImportRow{}, &ImportError{Line: s.lines.lineAt(s.lines.read), Message: err.Error(), fatal: true}
+		}
+		return ImportRow{}, err
+	}
+	line := s.lines.lineAt(s.dec.InputOffset() - int64(len(raw)))
+
+	var rec jsonRecord
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rec); err != nil {
+		return ImportRow{}, &ImportError{Line: line, Message: err.Error()}
+	}
+	return ImportRow{
+		Line:     line,
+		Title:    strings.TrimSpace(rec.Title),
+		Artist:   strings.TrimSpace(rec.Artist),
+		ArtistID: rec.ArtistID,
+		Genres:   rec.Genres,
+		Tags:     rec.Tags,
+	}, nil
+}
+
+// lineCounter wraps a reader and maps byte offsets back to line numbers.
+// Offsets must be queried in increasing order; only newlines not yet
+// passed are remembered, so memory stays bounded by the read-ahead.
+type lineCounter struct {
+	r        io.Reader
+	read     int64   // bytes read so far
+	pending  []int64 // offsets of newlines at or after the last query
+	newlines int     // newlines before the last queried offset
+}
+
+func (l *lineCounter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			l.pending = append(l.pending, l.read+int64(i))
+		}
+	}
+	l.read += int64(n)
+	return n, err
+}
+
+// lineAt returns the 1-based line number of the byte at offset.
+func (l *lineCounter) lineAt(offset int64) int {
+	i := 0
+	for i < len(l.pending) && l.pending[i] < offset {
+		i++
+	}
+	l.newlines += i
+	l.pending = l.pending[i:]
+	return l.newlines + 1
+}
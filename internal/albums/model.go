@@ -1,9 +1,31 @@
 package albums
 
-import "gorm.io/gorm"
+import (
+	"gin-quickstart/internal/artists"
+	"gin-quickstart/internal/genres"
+
+	"gorm.io/gorm"
+)
 
 type Album struct {
-	Title  string `json:"title" binding:"required"`
-	Artist string `json:"artist" binding:"required"`
+	Title string `json:"title"`
+	// Artist is the credited artist's display name, kept in step with the
+	// artists table.
+	Artist   string `json:"artist"`
+	ArtistID *uint  `json:"artist_id" gorm:"index"`
+	// ArtistDetail is the credited artist's record, only loaded when
+	// explicitly requested (?include=artist_detail). The foreign key itself
+	// is created by a migration.
+	ArtistDetail *artists.Artist `json:"artist_detail,omitempty" gorm:"foreignKey:ArtistID;constraint:-"`
+	// Version is bumped on every update and exposed as the ETag for optimistic locking.
+	Version uint `json:"version" gorm:"not null;default:1"`
+	// Tracks are only loaded when explicitly requested (?include=tracks).
+	Tracks []Track `json:"tracks,omitempty" gorm:"constraint:OnDelete:CASCADE"`
+	// Genres and Tags are assigned through their own endpoints.
+	Genres []genres.Genre `json:"genres,omitempty" gorm:"many2many:album_genres;constraint:OnDelete:CASCADE"`
+	Tags   []Tag          `json:"tags,omitempty" gorm:"many2many:album_tags;constraint:OnDelete:CASCADE"`
+	// CoverHash fingerprints the current cover image; empty means no cover.
+	CoverHash string `json:"-"`
+	CoverType string `json:"-"`
 	gorm.Model
 }
@@ -0,0 +1,102 @@
+package albums
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Media types accepted by PATCH /albums/:id.
+const (
+	MergePatchContentType = "application/merge-patch+json" // RFC 7396
+	JSONPatchContentType  = "application/json-patch+json"  // RFC 6902
+)
+
+var (
+	// ErrUnsupportedPatch is returned for a PATCH body in an unknown media type.
+	ErrUnsupportedPatch = errors.New("unsupported patch content type")
+	// ErrInvalidPatch is returned when a patch document cannot be parsed or applied.
+	ErrInvalidPatch = errors.New("invalid patch document")
+	// ErrInvalidAlbum is returned when an album fails validation.
+	ErrInvalidAlbum = errors.New("invalid album")
+)
+
+// albumDocument is the patchable view of an Album. Only these fields can be
+// changed through PATCH; ids and timestamps are managed by the server.
+type albumDocument struct {
+	Title    string `json:"title" binding:"required"`
+	Artist   string `json:"artist"`
+	ArtistID *uint  `json:"artist_id"`
+}
+
+// applyPatch applies patch to album according to contentType and returns the
+// columns whose values changed, keyed by column name.
+func applyPatch(album Album, patch []byte, contentType string) (map[string]any, error) {
+	original, err := json.Marshal(albumDocument{Title: album.Title, Artist: album.Artist, ArtistID: album.ArtistID})
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Apply the patch to the JSON document
+	var patched []byte
+	switch mediaType(contentType) {
+	case MergePatchContentType:
+		patched, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatchContentType:
+		var ops jsonpatch.Patch
+		if ops, err = jsonpatch.DecodePatch(patch); err == nil {
+			patched, err = ops.Apply(original)
+		}
+	default:
+		return nil, ErrUnsupportedPatch
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	// 2. Decode the result strictly so patches cannot touch unknown or read-only fields
+	var doc albumDocument
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAlbum, err)
+	}
+	if err := binding.Validator.ValidateStruct(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAlbum, err)
+	}
+
+	// 3. Collect only the columns that actually changed. A new artist_id wins
+	// over a renamed artist; the service resolves whichever one is left.
+	changes := map[string]any{}
+	if doc.Title != album.Title {
+		changes["title"] = doc.Title
+	}
+	switch {
+	case !sameArtistID(doc.ArtistID, album.ArtistID):
+		if doc.ArtistID == nil {
+			return nil, fmt.Errorf("%w: artist_id cannot be removed", ErrInvalidAlbum)
+		}
+		changes["artist_id"] = *doc.ArtistID
+	case doc.Artist != album.Artist:
+		changes["artist"] = doc.Artist
+	}
+	return changes, nil
+}
+
+func sameArtistID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// mediaType strips parameters such as charset from a Content-Type header.
+func mediaType(contentType string) string {
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
@@ -0,0 +1,62 @@
+package albums
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	artistID := uint(3)
+	album := Album{Title: "Blue Train", Artist: "John Coltrane", ArtistID: &artistID}
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        map[string]any
+		wantErr     error
+	}{
+		{"merge: title", MergePatchContentType, `{"title":"Giant Steps"}`, map[string]any{"title": "Giant Steps"}, nil},
+		{"merge: charset parameter", MergePatchContentType + "; charset=utf-8", `{"title":"Giant Steps"}`, map[string]any{"title": "Giant Steps"}, nil},
+		{"merge: unchanged value", MergePatchContentType, `{"title":"Blue Train"}`, map[string]any{}, nil},
+		{"merge: empty patch", MergePatchContentType, `{}`, map[string]any{}, nil},
+		{"merge: artist rename", MergePatchContentType, `{"artist":"Trane"}`, map[string]any{"artist": "Trane"}, nil},
+		{"merge: new artist_id wins over rename", MergePatchContentType, `{"artist":"Trane","artist_id":5}`, map[string]any{"artist_id": uint(5)}, nil},
+		{"merge: removing title", MergePatchContentType, `{"title":null}`, nil, ErrInvalidAlbum},
+		{"merge: removing artist_id", MergePatchContentType, `{"artist_id":null}`, nil, ErrInvalidAlbum},
+		{"merge: read-only field", MergePatchContentType, `{"version":9}`, nil, ErrInvalidAlbum},
+		{"merge: unknown field", MergePatchContentType, `{"rating":5}`, nil, ErrInvalidAlbum},
+		{"merge: wrong type", MergePatchContentType, `{"title":42}`, nil, ErrInvalidAlbum},
+		{"merge: malformed", MergePatchContentType, `{"title":`, nil, ErrInvalidPatch},
+
+		{"json patch: replace", JSONPatchContentType, `[{"op":"replace","path":"/title","value":"Giant Steps"}]`, map[string]any{"title": "Giant Steps"}, nil},
+		{"json patch: passing test", JSONPatchContentType, `[{"op":"test","path":"/title","value":"Blue Train"},{"op":"replace","path":"/artist_id","value":5}]`, map[string]any{"artist_id": uint(5)}, nil},
+		{"json patch: failing test", JSONPatchContentType, `[{"op":"test","path":"/title","value":"Ballads"},{"op":"replace","path":"/title","value":"x"}]`, nil, ErrInvalidPatch},
+		{"json patch: missing path", JSONPatchContentType, `[{"op":"replace","path":"/missing/deep","value":1}]`, nil, ErrInvalidPatch},
+		{"json patch: unknown op", JSONPatchContentType, `[{"op":"frobnicate","path":"/title"}]`, nil, ErrInvalidPatch},
+		{"json patch: not an array", JSONPatchContentType, `{"op":"replace","path":"/title","value":"x"}`, nil, ErrInvalidPatch},
+		{"json patch: remove title", JSONPatchContentType, `[{"op":"remove","path":"/title"}]`, nil, ErrInvalidAlbum},
+		{"json patch: add read-only field", JSONPatchContentType, `[{"op":"add","path":"/id","value":1}]`, nil, ErrInvalidAlbum},
+
+		{"plain JSON", "application/json", `{"title":"Giant Steps"}`, nil, ErrUnsupportedPatch},
+		{"no content type", "", `{"title":"Giant Steps"}`, nil, ErrUnsupportedPatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPatch(album, []byte(tt.patch), tt.contentType)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("applyPatch error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyPatch = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
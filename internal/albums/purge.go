@@ -0,0 +1,33 @@
+package albums
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartTrashPurger runs Service.PurgeExpired every interval until ctx is
+// cancelled. It returns immediately; the purge loop runs in its own goroutine.
+func StartTrashPurger(ctx context.Context, s Service, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := s.PurgeExpired()
+			if err != nil {
+				log.Printf("⚠️ trash purge failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("🗑️ purged %d expired album(s) from the trash", purged)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
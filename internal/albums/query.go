@@ -0,0 +1,301 @@
+package albums
+
+import (
+	"fmt"
+	"gin-quickstart/internal/genres"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// sortableColumns maps the sort keys accepted from clients to database columns.
+var sortableColumns = map[string]string{
+	"id":         "id",
+	"title":      "title",
+	"artist":     "artist",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// SortField is a single column in an ORDER BY clause.
+type SortField struct {
+	Column string
+	Desc   bool
+}
+
+// QueryOptions holds the filtering, sorting and pagination settings for album listings.
+type QueryOptions struct {
+	Title          string
+	Artist         string
+	ArtistID       uint
+	TitleContains  string
+	ArtistContains string
+	Genres         []string
+	GenreMatch     string
+	Tags           []string
+	TagMatch       string
+	Sort           []SortField
+	Limit          int
+	Offset         int
+
+	// CursorMode switches the listing to keyset pagination. Cursor is the
+	// opaque position returned as next_cursor; empty means the first page.
+	CursorMode bool
+	Cursor     string
+
+	// Fieldset picks the fields and relations loaded for each album.
+	Fieldset Fieldset
+}
+
+// ParseQueryOptions builds QueryOptions from the request's query string.
+// Pagination accepts either page/page_size or limit/offset.
+func ParseQueryOptions(q url.Values) (QueryOptions, error) {
+	opts := QueryOptions{
+		Title:          strings.TrimSpace(q.Get("title")),
+		Artist:         strings.TrimSpace(q.Get("artist")),
+		TitleContains:  strings.TrimSpace(q.Get("title_contains")),
+		ArtistContains: strings.TrimSpace(q.Get("artist_contains")),
+		Limit:          DefaultPageSize,
+	}
+
+	if raw := q.Get("artist_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return QueryOptions{}, fmt.Errorf("artist_id must be an integer")
+		}
+		opts.ArtistID = uint(id)
+	}
+
+	// 1. Classification (?genre=jazz,blues&genre_match=all)
+	var err error
+	opts.Genres = parseListParam(q, "genre", genres.Slugify)
+	if opts.GenreMatch, err = parseMatch(q, "genre_match"); err != nil {
+		return QueryOptions{}, err
+	}
+	opts.Tags = parseListParam(q, "tag", NormalizeTag)
+	if opts.TagMatch, err = parseMatch(q, "tag_match"); err != nil {
+		return QueryOptions{}, err
+	}
+
+	// 2. Sorting and sparse fieldsets
+	sort, err := parseSort(q.Get("sort"))
+	if err != nil {
+		return QueryOptions{}, err
+	}
+	opts.Sort = sort
+	if opts.Fieldset, err = ParseFieldset(q); err != nil {
+		return QueryOptions{}, err
+	}
+
+	// 3. Keyset pagination (?cursor=, empty for the first page)
+	if q.Has("cursor") {
+		if q.Has("page") || q.Has("offset") {
+			return QueryOptions{}, fmt.Errorf("cursor cannot be combined with page or offset")
+		}
+		opts.CursorMode = true
+		opts.Cursor = q.Get("cursor")
+		sizeKey := "limit"
+		if q.Has("page_size") {
+			sizeKey = "page_size"
+		}
+		if opts.Limit, err = parseInt(q, sizeKey, DefaultPageSize, 1, MaxPageSize); err != nil {
+			return QueryOptions{}, err
+		}
+		return opts, nil
+	}
+
+	// 4. Offset pagination
+	if opts.Limit, opts.Offset, err = parsePagination(q); err != nil {
+		return QueryOptions{}, err
+	}
+	return opts, nil
+}
+
+// parsePagination reads limit/offset, falling back to page/page_size.
+func parsePagination(q url.Values) (limit, offset int, err error) {
+	if q.Has("limit") || q.Has("offset") {
+		if limit, err = parseInt(q, "limit", DefaultPageSize, 1, MaxPageSize); err != nil {
+			return 0, 0, err
+		}
+		if offset, err = parseInt(q, "offset", 0, 0, -1); err != nil {
+			return 0, 0, err
+		}
+		return limit, offset, nil
+	}
+
+	if limit, err = parseInt(q, "page_size", DefaultPageSize, 1, MaxPageSize); err != nil {
+		return 0, 0, err
+	}
+	page, err := parseInt(q, "page", 1, 1, -1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return limit, (page - 1) * limit, nil
+}
+
+// parseSort turns "title,-created_at" into sort fields, rejecting unknown
+// columns. A key takes at most one "-" (descending) or "+" (ascending) prefix.
+func parseSort(raw string) ([]SortField, error) {
+	var fields []SortField
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, desc := part, false
+		switch part[0] {
+		case '-':
+			key, desc = part[1:], true
+		case '+':
+			key = part[1:]
+		}
+		column, ok := sortableColumns[key]
+		if !ok {
+			return nil, fmt.Errorf("cannot sort by %q", key)
+		}
+		fields = append(fields, SortField{Column: column, Desc: desc})
+	}
+	return fields, nil
+}
+
+// parseInt reads an integer query parameter bounded by min and max (max < 0 means unbounded).
+func parseInt(q url.Values, key string, def, min, max int) (int, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < min {
+		return 0, fmt.Errorf("%s must be an integer >= %d", key, min)
+	}
+	if max >= 0 && n > max {
+		return 0, fmt.Errorf("%s must not exceed %d", key, max)
+	}
+	return n, nil
+}
+
+// applyFilters adds the WHERE clauses described by opts to tx.
+func applyFilters(tx *gorm.DB, opts QueryOptions) *gorm.DB {
+	if opts.Title != "" {
+		tx = tx.Where("title = ?", opts.Title)
+	}
+	if opts.Artist != "" {
+		tx = tx.Where("artist = ?", opts.Artist)
+	}
+	if opts.ArtistID != 0 {
+		tx = tx.Where("artist_id = ?", opts.ArtistID)
+	}
+	if opts.TitleContains != "" {
+		tx = tx.Where("title ILIKE ?", "%"+escapeLike(opts.TitleContains)+"%")
+	}
+	if opts.ArtistContains != "" {
+		tx = tx.Where("artist ILIKE ?", "%"+escapeLike(opts.ArtistContains)+"%")
+	}
+	if len(opts.Genres) > 0 {
+		tx = classifiedAs(tx, "album_genres", "genre_id", "genres", "slug", opts.Genres, opts.GenreMatch)
+	}
+	if len(opts.Tags) > 0 {
+		tx = classifiedAs(tx, "album_tags", "tag_id", "tags", "name", opts.Tags, opts.TagMatch)
+	}
+	return tx
+}
+
+// applySort adds the ORDER BY clause described by opts to tx.
+// The primary key is always appended as a tie-breaker so pages are stable.
+func applySort(tx *gorm.DB, opts QueryOptions) *gorm.DB {
+	for _, f := range sortKeys(opts.Sort) {
+		if f.Desc {
+			tx = tx.Order(f.Column + " DESC")
+		} else {
+			tx = tx.Order(f.Column + " ASC")
+		}
+	}
+	return tx
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// ListMeta describes the position of a page within the full result set.
+type ListMeta struct {
+	Total    int64     `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+	Offset   int       `json:"offset"`
+	Links    ListLinks `json:"links"`
+}
+
+// ListLinks holds navigation links relative to the current request.
+type ListLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// buildListMeta computes the pagination meta block for a listing response.
+// Links keep every other query parameter of the original request intact.
+func buildListMeta(u *url.URL, limit, offset int, total int64) ListMeta {
+	meta := ListMeta{
+		Total:    total,
+		Page:     offset/limit + 1,
+		PageSize: limit,
+		Offset:   offset,
+		Links:    ListLinks{Self: u.RequestURI()},
+	}
+	if int64(offset+limit) < total {
+		meta.Links.Next = pageLink(u, offset+limit, limit)
+	}
+	if offset > 0 {
+		meta.Links.Prev = pageLink(u, max(offset-limit, 0), limit)
+	}
+	return meta
+}
+
+// pageLink rewrites u to point at the page starting at offset, preserving
+// whichever pagination style (page/page_size or limit/offset) the client used.
+func pageLink(u *url.URL, offset, limit int) string {
+	q := u.Query()
+	if q.Has("limit") || q.Has("offset") {
+		q.Set("limit", strconv.Itoa(limit))
+		q.Set("offset", strconv.Itoa(offset))
+	} else {
+		q.Set("page_size", strconv.Itoa(limit))
+		q.Set("page", strconv.Itoa(offset/limit+1))
+	}
+	link := *u
+	link.RawQuery = q.Encode()
+	return link.RequestURI()
+}
+
+// CursorMeta describes a keyset-paginated page.
+type CursorMeta struct {
+	PageSize   int       `json:"page_size"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Links      ListLinks `json:"links"`
+}
+
+// buildCursorMeta computes the meta block for a keyset-paginated response.
+func buildCursorMeta(u *url.URL, opts QueryOptions, next string) CursorMeta {
+	meta := CursorMeta{
+		PageSize:   opts.Limit,
+		NextCursor: next,
+		Links:      ListLinks{Self: u.RequestURI()},
+	}
+	if next != "" {
+		q := u.Query()
+		q.Set("cursor", next)
+		link := *u
+		link.RawQuery = q.Encode()
+		meta.Links.Next = link.RequestURI()
+	}
+	return meta
+}
@@ -0,0 +1,143 @@
+package albums
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []SortField
+		wantErr string
+	}{
+		{raw: "", want: nil},
+		{raw: "title", want: []SortField{{Column: "title"}}},
+		{raw: "-created_at", want: []SortField{{Column: "created_at", Desc: true}}},
+		{raw: "+artist", want: []SortField{{Column: "artist"}}},
+		{raw: " title , -id ", want: []SortField{{Column: "title"}, {Column: "id", Desc: true}}},
+		{raw: "title,,artist", want: []SortField{{Column: "title"}, {Column: "artist"}}},
+		{raw: "rating", wantErr: `cannot sort by "rating"`},
+		{raw: "title,-password", wantErr: `cannot sort by "password"`},
+		{raw: "--title", wantErr: `cannot sort by "-title"`},
+		{raw: "+-title", wantErr: `cannot sort by "-title"`},
+		{raw: "-", wantErr: `cannot sort by ""`},
+		{raw: "title desc", wantErr: `cannot sort by "title desc"`},
+		{raw: "Title", wantErr: `cannot sort by "Title"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parseSort(tt.raw)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseSort(%q) error = %v, want %q", tt.raw, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSort(%q): %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSort(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		check   func(t *testing.T, opts QueryOptions)
+		wantErr string
+	}{
+		{name: "defaults", query: "", check: func(t *testing.T, opts QueryOptions) {
+			if opts.Limit != DefaultPageSize || opts.Offset != 0 || opts.CursorMode {
+				t.Errorf("limit, offset, cursor mode = %d, %d, %v", opts.Limit, opts.Offset, opts.CursorMode)
+			}
+			if opts.GenreMatch != MatchAny || opts.TagMatch != MatchAny {
+				t.Errorf("match modes = %q, %q", opts.GenreMatch, opts.TagMatch)
+			}
+		}},
+		{name: "page and page_size", query: "page=3&page_size=10", check: func(t *testing.T, opts QueryOptions) {
+			if opts.Limit != 10 || opts.Offset != 20 {
+				t.Errorf("limit, offset = %d, %d, want 10, 20", opts.Limit, opts.Offset)
+			}
+		}},
+		{name: "limit and offset", query: "limit=5&offset=7", check: func(t *testing.T, opts QueryOptions) {
+			if opts.Limit != 5 || opts.Offset != 7 {
+				t.Errorf("limit, offset = %d, %d, want 5, 7", opts.Limit, opts.Offset)
+			}
+		}},
+		{name: "filters are trimmed", query: "title=+Blue+Train+&artist_contains=coltrane&artist_id=4", check: func(t *testing.T, opts QueryOptions) {
+			if opts.Title != "Blue Train" || opts.ArtistContains != "coltrane" || opts.ArtistID != 4 {
+				t.Errorf("opts = %+v", opts)
+			}
+		}},
+		{name: "sort", query: "sort=-updated_at,title", check: func(t *testing.T, opts QueryOptions) {
+			want := []SortField{{Column: "updated_at", Desc: true}, {Column: "title"}}
+			if !reflect.DeepEqual(opts.Sort, want) {
+				t.Errorf("sort = %+v, want %+v", opts.Sort, want)
+			}
+		}},
+		{name: "classification", query: "genre=jazz,Hard%20Bop&genre_match=ALL&tag=Live", check: func(t *testing.T, opts QueryOptions) {
+			if !reflect.DeepEqual(opts.Genres, []string{"hard-bop", "jazz"}) || opts.GenreMatch != MatchAll {
+				t.Errorf("genres = %q (%s)", opts.Genres, opts.GenreMatch)
+			}
+			if !reflect.DeepEqual(opts.Tags, []string{"live"}) {
+				t.Errorf("tags = %q", opts.Tags)
+			}
+		}},
+		{name: "duplicate classification", query: "genre=jazz,Jazz&genre=jazz&genre_match=all&tag=Live&tag=live", check: func(t *testing.T, opts QueryOptions) {
+			if !reflect.DeepEqual(opts.Genres, []string{"jazz"}) {
+				t.Errorf("genres = %q", opts.Genres)
+			}
+			if !reflect.DeepEqual(opts.Tags, []string{"live"}) {
+				t.Errorf("tags = %q", opts.Tags)
+			}
+		}},
+		{name: "first cursor page", query: "cursor=&limit=50", check: func(t *testing.T, opts QueryOptions) {
+			if !opts.CursorMode || opts.Cursor != "" || opts.Limit != 50 {
+				t.Errorf("cursor mode, cursor, limit = %v, %q, %d", opts.CursorMode, opts.Cursor, opts.Limit)
+			}
+		}},
+		{name: "fieldset", query: "fields=title,id&include=tracks", check: func(t *testing.T, opts QueryOptions) {
+			if !reflect.DeepEqual(opts.Fieldset.Fields, []string{"id", "title"}) || !reflect.DeepEqual(opts.Fieldset.Include, []string{"tracks"}) {
+				t.Errorf("fieldset = %+v", opts.Fieldset)
+			}
+		}},
+		{name: "unknown sort field", query: "sort=price", wantErr: `cannot sort by "price"`},
+		{name: "doubled direction", query: "sort=--title", wantErr: `cannot sort by "-title"`},
+		{name: "page zero", query: "page=0", wantErr: "page must be an integer >= 1"},
+		{name: "page_size too large", query: "page_size=101", wantErr: "page_size must not exceed 100"},
+		{name: "negative offset", query: "offset=-1", wantErr: "offset must be an integer >= 0"},
+		{name: "non-numeric limit", query: "limit=ten", wantErr: "limit must be an integer >= 1"},
+		{name: "bad artist_id", query: "artist_id=x", wantErr: "artist_id must be an integer"},
+		{name: "bad match mode", query: "genre=jazz&genre_match=most", wantErr: "genre_match must be"},
+		{name: "cursor with offset", query: "cursor=&offset=20", wantErr: "cursor cannot be combined with page or offset"},
+		{name: "cursor with page", query: "cursor=abc&page=2", wantErr: "cursor cannot be combined with page or offset"},
+		{name: "unknown field", query: "fields=title,password", wantErr: `unknown fields value "password"`},
+		{name: "unknown include", query: "include=reviews", wantErr: `unknown include value "reviews"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts, err := ParseQueryOptions(q)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseQueryOptions(%q) error = %v, want %q", tt.query, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQueryOptions(%q): %v", tt.query, err)
+			}
+			tt.check(t, opts)
+		})
+	}
+}
@@ -1,14 +1,50 @@
 package albums
 
-import "gorm.io/gorm"
+import (
+	"database/sql"
+	"gin-quickstart/internal/artists"
+	"gin-quickstart/internal/dberr"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 // Repository defines the interface for data access methods.
 type Repository interface {
-	FindAll() ([]Album, error)
+	FindAll(opts QueryOptions) ([]Album, int64, error)
+	FindAfter(opts QueryOptions, after *Cursor) ([]Album, error)
+	FindInBatches(opts QueryOptions, size int, fn func(batch []Album) error) error
+	Search(opts SearchOptions) ([]SearchResult, int64, error)
+	Suggest(query string, threshold float64, limit int) ([]string, error)
 	Create(album Album) (Album, error)
 	FindById(id uint) (Album, error)
-	Update(album Album) (Album, error)
-	Delete(id uint) error
+	Update(album Album, versions []uint) (Album, error)
+	UpdateFields(id uint, versions []uint, fields map[string]any) (Album, error)
+	Delete(id uint, versions []uint) error
+	FindTrashed(limit, offset int) ([]Album, int64, error)
+	Restore(id uint) (Album, error)
+	HardDelete(id uint, versions []uint) error
+	PurgeDeletedBefore(cutoff time.Time) ([]uint, error)
+	FindByIdUnscoped(id uint) (Album, error)
+	CreateRevision(rev Revision) error
+	FindRevisions(albumID uint, limit, offset int) ([]Revision, int64, error)
+	FindRevision(albumID, revID uint) (Revision, error)
+	Transaction(fn func(repo Repository) error) error
+	FindByIdFields(id uint, fs Fieldset) (Album, error)
+	FindTracks(albumID uint) ([]Track, error)
+	FindTrack(albumID, trackID uint) (Track, error)
+	NextTrackPosition(albumID, disc uint) (uint, error)
+	CreateTrack(track Track) (Track, error)
+	UpdateTrack(track Track) (Track, error)
+	DeleteTrack(albumID, trackID uint) error
+	SetTrackPositions(albumID uint, positions map[uint]uint) error
+	ReplaceGenres(albumID uint, genreIDs []uint) error
+	UpsertTags(names []string) ([]Tag, error)
+	ReplaceTags(albumID uint, tagIDs []uint) error
+	Facets(opts QueryOptions) (Facets, error)
+	FindDuplicate(title string, artistID uint) (Album, error)
+	Artists() artists.Repository
 }
 
 // repository is the concrete implementation of the Repository interface.
@@ -21,14 +57,94 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{DB: db}
 }
 
-func (r *repository) FindAll() ([]Album, error) {
+func (r *repository) FindAll(opts QueryOptions) ([]Album, int64, error) {
+	var (
+		albums []Album
+		total  int64
+	)
+	query := applyFilters(r.DB.Model(&Album{}), opts)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := opts.Fieldset.apply(applySort(query, opts), opts.Sort).Limit(opts.Limit).Offset(opts.Offset).Find(&albums).Error; err != nil {
+		return nil, 0, err
+	}
+	return albums, total, nil
+}
+
+func (r *repository) FindAfter(opts QueryOptions, after *Cursor) ([]Album, error) {
 	var albums []Album
-	if err := r.DB.Find(&albums).Error; err != nil {
+	query := applyFilters(r.DB.Model(&Album{}), opts)
+	if after != nil {
+		query = applyKeyset(query, opts.Sort, *after)
+	}
+	if err := opts.Fieldset.apply(applySort(query, opts), opts.Sort).Limit(opts.Limit).Find(&albums).Error; err != nil {
 		return nil, err
 	}
 	return albums, nil
 }
 
+// FindInBatches passes every album matching the filters in opts to fn, size
+// albums at a time in primary key order. Sorting and pagination are ignored.
+func (r *repository) FindInBatches(opts QueryOptions, size int, fn func(batch []Album) error) error {
+	var batch []Album
+	query := preloadClassification(applyFilters(r.DB.Model(&Album{}), opts))
+	return query.FindInBatches(&batch, size, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+func (r *repository) Search(opts SearchOptions) ([]SearchResult, int64, error) {
+	var (
+		results []SearchResult
+		total   int64
+	)
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if opts.Mode == SearchModeFuzzy {
+			if err := setSimilarityThreshold(tx, opts.Threshold); err != nil {
+				return err
+			}
+		}
+		if err := searchMatch(tx.Model(&Album{}), opts).Count(&total).Error; err != nil {
+			return err
+		}
+		return searchSelect(searchMatch(tx.Model(&Album{}), opts), opts).
+			Order("rank DESC").Order("albums.id ASC").
+			Limit(opts.Limit).Offset(opts.Offset).
+			Scan(&results).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
+
+func (r *repository) Suggest(query string, threshold float64, limit int) ([]string, error) {
+	var terms []string
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := setSimilarityThreshold(tx, threshold); err != nil {
+			return err
+		}
+		return tx.Raw(`
+			SELECT term FROM (
+				SELECT title AS term, similarity(title, @q) AS score FROM albums
+				WHERE deleted_at IS NULL AND title % @q
+				UNION ALL
+				SELECT artist AS term, similarity(artist, @q) AS score FROM albums
+				WHERE deleted_at IS NULL AND artist % @q
+			) candidates
+			GROUP BY term
+			ORDER BY max(score) DESC, term ASC
+			LIMIT @limit`,
+			sql.Named("q", query), sql.Named("limit", limit),
+		).Scan(&terms).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return terms, nil
+}
+
 func (r *repository) Create(album Album) (Album, error) {
 	if err := r.DB.Create(&album).Error; err != nil {
 		return Album{}, err
@@ -38,22 +154,411 @@ func (r *repository) Create(album Album) (Album, error) {
 
 func (r *repository) FindById(id uint) (Album, error) {
 	var album Album
-	if err := r.DB.First(&album, "id = ?", id).Error; err != nil {
+	if err := preloadClassification(r.DB).First(&album, "id = ?", id).Error; err != nil {
+		return Album{}, err
+	}
+	return album, nil
+}
+
+func (r *repository) Update(album Album, versions []uint) (Album, error) {
+	// Only write the editable columns so CreatedAt is never clobbered.
+	return r.UpdateFields(album.ID, versions, map[string]any{
+		"title":     album.Title,
+		"artist":    album.Artist,
+		"artist_id": album.ArtistID,
+	})
+}
+
+// UpdateFields writes fields and bumps the version in a single UPDATE. When
+// versions is non-empty the row is only updated if its current version is
+// one of them, which makes the optimistic lock check atomic.
+func (r *repository) UpdateFields(id uint, versions []uint, fields map[string]any) (Album, error) {
+	values := make(map[string]any, len(fields)+1)
+	for k, v := range fields {
+		values[k] = v
+	}
+	values["version"] = gorm.Expr("version + 1")
+
+	query := r.DB.Model(&Album{}).Where("id = ?", id)
+	if len(versions) > 0 {
+		query = query.Where("version IN ?", versions)
+	}
+	result := query.Updates(values)
+	if result.Error != nil {
+		return Album{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Album{}, r.conflictOrNotFound(id)
+	}
+	return r.FindById(id)
+}
+
+func (r *repository) Delete(id uint, versions []uint) error {
+	query := r.DB.Where("id = ?", id)
+	if len(versions) > 0 {
+		query = query.Where("version IN ?", versions)
+	}
+	result := query.Delete(&Album{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.conflictOrNotFound(id)
+	}
+	return nil
+}
+
+func (r *repository) FindTrashed(limit, offset int) ([]Album, int64, error) {
+	var (
+		albums []Album
+		total  int64
+	)
+	query := r.DB.Unscoped().Model(&Album{}).Where("deleted_at IS NOT NULL")
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("deleted_at DESC").Order("id ASC").Limit(limit).Offset(offset).Find(&albums).Error; err != nil {
+		return nil, 0, err
+	}
+	return albums, total, nil
+}
+
+func (r *repository) Restore(id uint) (Album, error) {
+	result := r.DB.Unscoped().Model(&Album{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]any{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	if result.Error != nil {
+		return Album{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// Either the album does not exist at all or it is not in the trash.
+		if _, err := r.FindById(id); err != nil {
+			return Album{}, err
+		}
+		return Album{}, ErrNotInTrash
+	}
+	return r.FindById(id)
+}
+
+func (r *repository) HardDelete(id uint, versions []uint) error {
+	query := r.DB.Unscoped().Where("id = ?", id)
+	if len(versions) > 0 {
+		query = query.Where("version IN ?", versions)
+	}
+	result := query.Delete(&Album{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.DB.Unscoped().Model(&Album{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return dberr.ErrNotFound
+		}
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// PurgeDeletedBefore permanently deletes albums trashed before cutoff and
+// returns their IDs.
+func (r *repository) PurgeDeletedBefore(cutoff time.Time) ([]uint, error) {
+	var purged []Album
+	err := r.DB.Unscoped().
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Delete(&purged).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(purged))
+	for _, album := range purged {
+		ids = append(ids, album.ID)
+	}
+	return ids, nil
+}
+
+func (r *repository) FindByIdUnscoped(id uint) (Album, error) {
+	var album Album
+	if err := preloadClassification(r.DB.Unscoped()).First(&album, "id = ?", id).Error; err != nil {
+		return Album{}, err
+	}
+	return album, nil
+}
+
+func (r *repository) CreateRevision(rev Revision) error {
+	return r.DB.Create(&rev).Error
+}
+
+func (r *repository) FindRevisions(albumID uint, limit, offset int) ([]Revision, int64, error) {
+	var (
+		revisions []Revision
+		total     int64
+	)
+	query := r.DB.Model(&Revision{}).Where("album_id = ?", albumID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&revisions).Error; err != nil {
+		return nil, 0, err
+	}
+	return revisions, total, nil
+}
+
+func (r *repository) FindRevision(albumID, revID uint) (Revision, error) {
+	var rev Revision
+	if err := r.DB.First(&rev, "id = ? AND album_id = ?", revID, albumID).Error; err != nil {
+		return Revision{}, err
+	}
+	return rev, nil
+}
+
+// FindByIdFields loads an album with only the fields and relations in fs.
+func (r *repository) FindByIdFields(id uint, fs Fieldset) (Album, error) {
+	var album Album
+	if err := fs.apply(r.DB, nil).First(&album, "id = ?", id).Error; err != nil {
 		return Album{}, err
 	}
 	return album, nil
 }
 
-func (r *repository) Update(album Album) (Album, error) {
-	if err := r.DB.Save(&album).Error; err != nil {
+func (r *repository) FindTracks(albumID uint) ([]Track, error) {
+	var tracks []Track
+	if err := orderTracks(r.DB.Where("album_id = ?", albumID)).Find(&tracks).Error; err != nil {
+		return nil, err
+	}
+	return tracks, nil
+}
+
+func (r *repository) FindTrack(albumID, trackID uint) (Track, error) {
+	var track Track
+	if err := r.DB.First(&track, "id = ? AND album_id = ?", trackID, albumID).Error; err != nil {
+		return Track{}, err
+	}
+	return track, nil
+}
+
+func (r *repository) NextTrackPosition(albumID, disc uint) (uint, error) {
+	var last uint
+	err := r.DB.Model(&Track{}).
+		Where("album_id = ? AND disc_number = ?", albumID, disc).
+		Select("COALESCE(MAX(position), 0)").Scan(&last).Error
+	if err != nil {
+		return 0, err
+	}
+	return last + 1, nil
+}
+
+func (r *repository) CreateTrack(track Track) (Track, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&track).Error; err != nil {
+			return err
+		}
+		return touchAlbum(tx, track.AlbumID)
+	})
+	if err != nil {
+		return Track{}, err
+	}
+	return track, nil
+}
+
+func (r *repository) UpdateTrack(track Track) (Track, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&track).
+			Where("album_id = ?", track.AlbumID).
+			Select("disc_number", "position", "title", "duration", "isrc").
+			Updates(track)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return dberr.ErrNotFound
+		}
+		return touchAlbum(tx, track.AlbumID)
+	})
+	if err != nil {
+		return Track{}, err
+	}
+	return r.FindTrack(track.AlbumID, track.ID)
+}
+
+func (r *repository) DeleteTrack(albumID, trackID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&Track{}, "id = ? AND album_id = ?", trackID, albumID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return dberr.ErrNotFound
+		}
+		return touchAlbum(tx, albumID)
+	})
+}
+
+func (r *repository) SetTrackPositions(albumID uint, positions map[uint]uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		for trackID, position := range positions {
+			err := tx.Model(&Track{}).
+				Where("id = ? AND album_id = ?", trackID, albumID).
+				Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+		return touchAlbum(tx, albumID)
+	})
+}
+
+// touchAlbum moves the album's updated_at forward after a change to its
+// tracks, so its Last-Modified covers tracks that were deleted. The version
+// is left alone: track writes do not invalidate the album's If-Match tags.
+func touchAlbum(tx *gorm.DB, albumID uint) error {
+	return tx.Model(&Album{}).Where("id = ?", albumID).UpdateColumn("updated_at", time.Now()).Error
+}
+
+// ReplaceGenres sets the album's genres to exactly genreIDs.
+func (r *repository) ReplaceGenres(albumID uint, genreIDs []uint) error {
+	return r.replaceLinks("album_genres", "genre_id", albumID, genreIDs)
+}
+
+// UpsertTags returns the tags with the given names, creating missing ones.
+func (r *repository) UpsertTags(names []string) ([]Tag, error) {
+	tags := make([]Tag, 0, len(names))
+	if len(names) == 0 {
+		return tags, nil
+	}
+	for _, name := range names {
+		tags = append(tags, Tag{Name: name})
+	}
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoNothing: true,
+	}).Create(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	tags = tags[:0]
+	if err := r.DB.Where("name IN ?", names).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ReplaceTags sets the album's tags to exactly tagIDs.
+func (r *repository) ReplaceTags(albumID uint, tagIDs []uint) error {
+	return r.replaceLinks("album_tags", "tag_id", albumID, tagIDs)
+}
+
+// Facets counts the albums matching opts per genre and per tag. Pagination
+// and sorting in opts are ignored.
+func (r *repository) Facets(opts QueryOptions) (Facets, error) {
+	facets := Facets{Genres: []GenreFacet{}, Tags: []TagFacet{}}
+	matching := applyFilters(r.DB.Model(&Album{}), opts).Select("albums.id")
+
+	err := r.DB.Table("album_genres AS ag").
+		Select("g.slug, g.name, COUNT(*) AS count").
+		Joins("JOIN genres AS g ON g.id = ag.genre_id").
+		Where("ag.album_id IN (?)", matching).
+		Group("g.id, g.slug, g.name").
+		Order("count DESC").Order("g.name ASC").
+		Scan(&facets.Genres).Error
+	if err != nil {
+		return Facets{}, err
+	}
+
+	err = r.DB.Table("album_tags AS tl").
+		Select("t.name, COUNT(*) AS count").
+		Joins("JOIN tags AS t ON t.id = tl.tag_id").
+		Where("tl.album_id IN (?)", matching).
+		Group("t.id, t.name").
+		Order("count DESC").Order("t.name ASC").
+		Limit(MaxTagFacets).
+		Scan(&facets.Tags).Error
+	if err != nil {
+		return Facets{}, err
+	}
+	return facets, nil
+}
+
+// replaceLinks rewrites the rows of a many-to-many join table for one album.
+func (r *repository) replaceLinks(table, fk string, albumID uint, ids []uint) error {
+	if err := r.DB.Exec("DELETE FROM "+table+" WHERE album_id = ?", albumID).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	rows := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, map[string]any{"album_id": albumID, fk: id})
+	}
+	return r.DB.Table(table).Create(rows).Error
+}
+
+// FindDuplicate finds an album by the same artist whose title matches,
+// ignoring case.
+func (r *repository) FindDuplicate(title string, artistID uint) (Album, error) {
+	var album Album
+	if err := r.DB.First(&album, "lower(title) = lower(?) AND artist_id = ?", title, artistID).Error; err != nil {
 		return Album{}, err
 	}
 	return album, nil
 }
 
-func (r *repository) Delete(id uint) error {
-	if err := r.DB.Delete(&Album{}, "id = ?", id).Error; err != nil {
+// Artists returns an artists repository sharing this repository's
+// connection, so artist lookups take part in the same transaction.
+func (r *repository) Artists() artists.Repository {
+	return artists.NewRepository(r.DB)
+}
+
+// FollowArtistRename is the artists.RenameHook that keeps the albums
+// credited to an artist, trashed ones included, in step with its name. Albums
+// keep a denormalized copy of the name for search and sorting; each renamed
+// album gets a new version and a revision like any other update.
+func FollowArtistRename(tx *gorm.DB, artist artists.Artist, actorID uint) error {
+	var credited []Album
+	err := preloadClassification(tx.Unscoped()).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("artist_id = ? AND artist <> ?", artist.ID, artist.Name).
+		Order("id ASC").
+		Find(&credited).Error
+	if err != nil || len(credited) == 0 {
 		return err
 	}
-	return nil
+
+	ids := make([]uint, len(credited))
+	revisions := make([]Revision, len(credited))
+	for i, before := range credited {
+		after := before
+		after.Artist, after.Version = artist.Name, before.Version+1
+		ids[i] = before.ID
+		revisions[i] = Revision{
+			AlbumID: before.ID, Action: ActionUpdate, ActorID: actorID,
+			Before: snapshotOf(before), After: snapshotOf(after),
+		}
+	}
+	err = tx.Unscoped().Model(&Album{}).Where("id IN ?", ids).
+		Updates(map[string]any{"artist": artist.Name, "version": gorm.Expr("version + 1")}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Create(&revisions).Error
+}
+
+// Transaction runs fn with a repository bound to a single database transaction.
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{DB: tx})
+	})
+}
+
+// conflictOrNotFound explains why a guarded write touched no rows: either the
+// album does not exist or its version has moved on.
+func (r *repository) conflictOrNotFound(id uint) error {
+	if _, err := r.FindById(id); err != nil {
+		return err
+	}
+	return ErrVersionMismatch
 }
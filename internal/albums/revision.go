@@ -0,0 +1,143 @@
+package albums
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Revision actions recorded in the album history.
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionRestore  = "restore"
+	ActionPurge    = "purge"
+	ActionRollback = "rollback"
+)
+
+// ErrRevisionNotRestorable is returned when rolling back to a revision that
+// has no after-state, such as a delete.
+var ErrRevisionNotRestorable = errors.New("revision has no state to roll back to")
+
+// Snapshot is the state of an album captured in a revision. Genres holds
+// genre slugs and Tags tag names; they are nil in revisions recorded before
+// classification was tracked. CoverHash and CoverType identify the cover
+// image; rollbacks leave the cover alone, since replaced images are deleted.
+type Snapshot struct {
+	Title     string   `json:"title"`
+	Artist    string   `json:"artist"`
+	ArtistID  *uint    `json:"artist_id,omitempty"`
+	Genres    []string `json:"genres"`
+	Tags      []string `json:"tags"`
+	CoverHash string   `json:"cover_hash,omitempty"`
+	CoverType string   `json:"cover_type,omitempty"`
+	Version   uint     `json:"version"`
+}
+
+// Value stores the snapshot as JSON.
+func (s Snapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads a snapshot stored as JSON.
+func (s *Snapshot) Scan(value any) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("cannot scan %T into Snapshot", value)
+	}
+}
+
+// snapshotOf captures the revisioned fields of album, including its genres
+// and tags, which must have been loaded.
+func snapshotOf(album Album) *Snapshot {
+	s := &Snapshot{
+		Title: album.Title, Artist: album.Artist, ArtistID: album.ArtistID, Version: album.Version,
+		Genres: []string{}, Tags: []string{}, CoverHash: album.CoverHash, CoverType: album.CoverType,
+	}
+	for _, g := range album.Genres {
+		s.Genres = append(s.Genres, g.Slug)
+	}
+	for _, t := range album.Tags {
+		s.Tags = append(s.Tags, t.Name)
+	}
+	return s
+}
+
+// Revision is one entry in an album's change history.
+type Revision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	AlbumID   uint      `json:"album_id" gorm:"index;not null"`
+	Action    string    `json:"action" gorm:"not null"`
+	ActorID   uint      `json:"actor_id"`
+	Before    *Snapshot `json:"before" gorm:"type:jsonb"`
+	After     *Snapshot `json:"after" gorm:"type:jsonb"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName overrides the default "revisions" table name.
+func (Revision) TableName() string {
+	return "album_revisions"
+}
+
+// FieldChange describes how one field changed in a revision.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
+
+// RevisionWithDiff is a revision together with its field-level diff.
+type RevisionWithDiff struct {
+	Revision
+	Changes []FieldChange `json:"changes"`
+}
+
+// diffSnapshots lists the fields that differ between before and after. A nil
+// snapshot stands for "no album", so every field of the other side appears.
+func diffSnapshots(before, after *Snapshot) []FieldChange {
+	from, to := snapshotFields(before), snapshotFields(after)
+
+	keys := make([]string, 0, len(from)+len(to))
+	seen := map[string]bool{}
+	for k := range from {
+		keys, seen[k] = append(keys, k), true
+	}
+	for k := range to {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []FieldChange{}
+	for _, k := range keys {
+		if !reflect.DeepEqual(from[k], to[k]) {
+			changes = append(changes, FieldChange{Field: k, From: from[k], To: to[k]})
+		}
+	}
+	return changes
+}
+
+// snapshotFields flattens a snapshot into its JSON fields.
+func snapshotFields(s *Snapshot) map[string]any {
+	fields := map[string]any{}
+	if s == nil {
+		return fields
+	}
+	b, _ := json.Marshal(s)
+	_ = json.Unmarshal(b, &fields)
+	return fields
+}
@@ -0,0 +1,159 @@
+package albums
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// ErrEmptySearch is returned when a search query has no searchable terms.
+var ErrEmptySearch = errors.New("search query must contain at least one letter or digit")
+
+// SearchMode selects how a search query is matched against albums.
+type SearchMode string
+
+const (
+	// SearchModeExact matches a case-insensitive substring of title or artist.
+	SearchModeExact SearchMode = "exact"
+	// SearchModeFullText uses the tsvector index with prefix matching.
+	SearchModeFullText SearchMode = "fulltext"
+	// SearchModeFuzzy uses pg_trgm similarity to tolerate typos.
+	SearchModeFuzzy SearchMode = "fuzzy"
+)
+
+// MaxSuggestions caps the "did you mean" list.
+const MaxSuggestions = 5
+
+// SearchOptions holds the query, mode and pagination for an album search.
+type SearchOptions struct {
+	Query  string
+	Mode   SearchMode
+	Limit  int
+	Offset int
+
+	// Threshold is the minimum pg_trgm similarity for fuzzy matches and
+	// suggestions. It is filled in from configuration by the service.
+	Threshold float64
+}
+
+// SearchResult is an album matched by a search, with its relevance score and,
+// in full-text mode, highlighted snippets of the matching fields.
+type SearchResult struct {
+	Album
+	Rank            float64 `json:"rank" gorm:"column:rank"`
+	TitleHighlight  string  `json:"title_highlight,omitempty" gorm:"column:title_highlight"`
+	ArtistHighlight string  `json:"artist_highlight,omitempty" gorm:"column:artist_highlight"`
+}
+
+// SearchPage is one page of search results plus "did you mean" suggestions,
+// which are only populated when the query had no exact hits.
+type SearchPage struct {
+	Results     []SearchResult
+	Total       int64
+	Suggestions []string
+}
+
+// ParseSearchOptions builds SearchOptions from the request's query string.
+func ParseSearchOptions(q url.Values) (SearchOptions, error) {
+	opts := SearchOptions{
+		Query: strings.TrimSpace(q.Get("q")),
+		Mode:  SearchMode(strings.ToLower(q.Get("mode"))),
+	}
+
+	switch opts.Mode {
+	case "":
+		opts.Mode = SearchModeFullText
+	case SearchModeExact, SearchModeFullText, SearchModeFuzzy:
+	default:
+		return SearchOptions{}, fmt.Errorf("unknown search mode %q (expected exact, fulltext or fuzzy)", opts.Mode)
+	}
+	if toPrefixTSQuery(opts.Query) == "" {
+		return SearchOptions{}, ErrEmptySearch
+	}
+
+	var err error
+	if opts.Limit, opts.Offset, err = parsePagination(q); err != nil {
+		return SearchOptions{}, err
+	}
+	return opts, nil
+}
+
+// toPrefixTSQuery turns free text into a tsquery that ANDs every word as a
+// prefix match, e.g. "kind blu" becomes "kind:* & blu:*". Anything that is
+// not a letter or digit is treated as a separator, so user input can never
+// inject tsquery operators.
+func toPrefixTSQuery(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// searchMatch scopes tx to albums matching opts in the requested mode.
+func searchMatch(tx *gorm.DB, opts SearchOptions) *gorm.DB {
+	switch opts.Mode {
+	case SearchModeExact:
+		pattern := "%" + escapeLike(opts.Query) + "%"
+		return tx.Where("albums.title ILIKE ? OR albums.artist ILIKE ?", pattern, pattern)
+	case SearchModeFuzzy:
+		return tx.Where("albums.title % ? OR albums.artist % ?", opts.Query, opts.Query)
+	default:
+		// The parsed query is exposed to the rest of the statement as search_query.
+		return tx.
+			Joins("CROSS JOIN to_tsquery('simple', ?) AS search_query", toPrefixTSQuery(opts.Query)).
+			Where("albums.search_vector @@ search_query")
+	}
+}
+
+// searchSelect selects the album columns plus the mode's relevance score and,
+// for full-text search, highlighted snippets.
+func searchSelect(tx *gorm.DB, opts SearchOptions) *gorm.DB {
+	switch opts.Mode {
+	case SearchModeExact:
+		return tx.Select(`albums.*,
+			CASE WHEN lower(albums.title) = lower(?) OR lower(albums.artist) = lower(?) THEN 1.0 ELSE 0.5 END AS rank`,
+			opts.Query, opts.Query)
+	case SearchModeFuzzy:
+		return tx.Select(`albums.*,
+			GREATEST(similarity(albums.title, ?), similarity(albums.artist, ?)) AS rank`,
+			opts.Query, opts.Query)
+	default:
+		return tx.Select(`albums.*,
+			ts_rank(albums.search_vector, search_query) AS rank,
+			ts_headline('simple', ` + escapeHTMLSQL("albums.title") + `, search_query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS title_highlight,
+			ts_headline('simple', ` + escapeHTMLSQL("albums.artist") + `, search_query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS artist_highlight`)
+	}
+}
+
+// escapeHTMLSQL wraps the SQL expression expr so that its value is HTML
+// escaped, leaving the <mark> tags added by ts_headline as the only markup
+// in a snippet. Entities are not words to the text search parser, so
+// highlighting is unaffected.
+func escapeHTMLSQL(expr string) string {
+	return `replace(replace(replace(replace(replace(` + expr +
+		`, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
+
+// setSimilarityThreshold sets pg_trgm's % operator threshold for the current
+// transaction only, so pooled connections are left untouched.
+func setSimilarityThreshold(tx *gorm.DB, threshold float64) error {
+	return tx.Exec("SELECT set_config('pg_trgm.similarity_threshold', ?, true)", fmt.Sprint(threshold)).Error
+}
+
+// hasExactHit reports whether any result contains query verbatim (ignoring case).
+func hasExactHit(results []SearchResult, query string) bool {
+	q := strings.ToLower(query)
+	for _, r := range results {
+		if strings.Contains(strings.ToLower(r.Title), q) || strings.Contains(strings.ToLower(r.Artist), q) {
+			return true
+		}
+	}
+	return false
+}
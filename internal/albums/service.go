@@ -1,49 +1,893 @@
 package albums
 
-import "gorm.io/gorm"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"gin-quickstart/internal/artists"
+	"gin-quickstart/internal/config"
+	"gin-quickstart/internal/dberr"
+	"gin-quickstart/internal/genres"
+	"gin-quickstart/internal/storage"
+	"io"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotInTrash is returned when restoring an album that is not soft-deleted.
+var ErrNotInTrash = errors.New("album is not in the trash")
 
 // Service defines the methods for business logic.
 type Service interface {
-	FindAll() ([]Album, error)
-	Create(album Album) (Album, error)
+	FindAll(opts QueryOptions) ([]Album, int64, error)
+	FindAllAfter(opts QueryOptions) ([]Album, string, error)
+	Search(opts SearchOptions) (SearchPage, error)
+	Create(album Album, actorID uint) (Album, error)
 	FindById(id uint) (Album, error)
-	Update(album Album) (Album, error)
-	Delete(id uint) error
+	Update(album Album, cond IfMatch, actorID uint) (Album, error)
+	Patch(id uint, patch []byte, contentType string, cond IfMatch, actorID uint) (Album, error)
+	Delete(id uint, cond IfMatch, actorID uint) error
+	FindTrashed(limit, offset int) ([]Album, int64, error)
+	Restore(id uint, actorID uint) (Album, error)
+	Purge(id uint, cond IfMatch, actorID uint) error
+	PurgeExpired() (int64, error)
+	FindRevisions(albumID uint, limit, offset int) ([]RevisionWithDiff, int64, error)
+	Rollback(albumID, revID uint, cond IfMatch, actorID uint) (Album, error)
+	FindByArtist(artistID uint, opts QueryOptions) ([]Album, int64, error)
+	SetGenres(albumID uint, slugs []string, cond IfMatch, actorID uint) (Album, error)
+	SetTags(albumID uint, tags []string, cond IfMatch, actorID uint) (Album, error)
+	Facets(opts QueryOptions) (Facets, error)
+	Export(opts QueryOptions, fn func(batch []Album) error) error
+	UploadCover(albumID uint, image io.Reader, cond IfMatch, actorID uint) (Album, error)
+	OpenCover(albumID uint, rendition string) (CoverBlob, error)
+	Import(src ImportSource, opts ImportOptions, actorID uint) (ImportReport, error)
+	FindByIdFields(id uint, fs Fieldset) (Album, error)
+	FindTracks(albumID uint) ([]Track, error)
+	CreateTrack(albumID uint, track Track) (Track, error)
+	UpdateTrack(albumID, trackID uint, track Track) (Track, error)
+	DeleteTrack(albumID, trackID uint) error
+	ReorderTracks(albumID uint, order TrackOrder) ([]Track, error)
+}
+
+// ArtistResolver looks up the artist an album is credited to.
+type ArtistResolver interface {
+	FindById(id uint) (artists.Artist, error)
+	FindOrCreateByName(name string) (artists.Artist, error)
+}
+
+// GenreResolver looks up genres in the admin-managed vocabulary.
+type GenreResolver interface {
+	FindBySlugs(slugs []string) ([]genres.Genre, error)
 }
 
 // service is the concrete implementation of Service.
 type service struct {
-	repo Repository
+	repo    Repository
+	artists ArtistResolver
+	genres  GenreResolver
+	blobs   storage.Storage
+	cfg     config.Config
 }
 
 // NewService is the constructor.
-func NewService(r Repository) Service {
-	return &service{repo: r}
+func NewService(r Repository, a ArtistResolver, g GenreResolver, blobs storage.Storage, cfg config.Config) Service {
+	return &service{repo: r, artists: a, genres: g, blobs: blobs, cfg: cfg}
+}
+
+func (s *service) FindAll(opts QueryOptions) ([]Album, int64, error) {
+	return s.repo.FindAll(opts)
+}
+
+// FindAllAfter returns the page following opts.Cursor and the cursor for the
+// page after it, which is empty once the listing is exhausted.
+func (s *service) FindAllAfter(opts QueryOptions) ([]Album, string, error) {
+	secret := []byte(s.cfg.App.CursorSecret)
+
+	var after *Cursor
+	if opts.Cursor != "" {
+		c, err := DecodeCursor(opts.Cursor, opts.Sort, secret)
+		if err != nil {
+			return nil, "", err
+		}
+		after = &c
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	limit := opts.Limit
+	opts.Limit++
+	albums, err := s.repo.FindAfter(opts, after)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(albums) <= limit {
+		return albums, "", nil
+	}
+
+	albums = albums[:limit]
+	next, err := EncodeCursor(newCursor(opts.Sort, albums[limit-1]), secret)
+	if err != nil {
+		return nil, "", err
+	}
+	return albums, next, nil
 }
 
-func (s *service) FindAll() ([]Album, error) {
-	return s.repo.FindAll()
+// Search runs opts in its requested mode. When nothing matches, or a fuzzy
+// search found only approximate matches, it adds "did you mean" suggestions.
+func (s *service) Search(opts SearchOptions) (SearchPage, error) {
+	opts.Threshold = s.cfg.Search.SimilarityThreshold
+
+	results, total, err := s.repo.Search(opts)
+	if err != nil {
+		return SearchPage{}, err
+	}
+	page := SearchPage{Results: results, Total: total}
+
+	if total == 0 || (opts.Mode == SearchModeFuzzy && !hasExactHit(results, opts.Query)) {
+		if page.Suggestions, err = s.repo.Suggest(opts.Query, opts.Threshold, MaxSuggestions); err != nil {
+			return SearchPage{}, err
+		}
+	}
+	return page, nil
 }
 
-func (s *service) Create(album Album) (Album, error) {
-	return s.repo.Create(album)
+// Every write below runs in a transaction together with the revision that
+// records it, so the history can never disagree with the albums table.
+
+func (s *service) Create(album Album, actorID uint) (Album, error) {
+	album.Version = 1
+	// Tracks, genres and tags are managed through their own endpoints.
+	album.Tracks, album.Genres, album.Tags = nil, nil, nil
+	album.ArtistDetail = nil
+
+	artist, err := s.creditArtist(album.ArtistID, album.Artist)
+	if err != nil {
+		return Album{}, err
+	}
+	album.ArtistID, album.Artist = &artist.ID, artist.Name
+
+	var created Album
+	err = s.repo.Transaction(func(repo Repository) error {
+		var err error
+		if created, err = repo.Create(album); err != nil {
+			return err
+		}
+		return repo.CreateRevision(Revision{
+			AlbumID: created.ID, Action: ActionCreate, ActorID: actorID,
+			After: snapshotOf(created),
+		})
+	})
+	return created, err
 }
 
 func (s *service) FindById(id uint) (Album, error) {
 	return s.repo.FindById(id)
 }
 
-func (s *service) Update(album Album) (Album, error) {
-	exit, err := s.repo.FindById(album.ID)
+func (s *service) Update(album Album, cond IfMatch, actorID uint) (Album, error) {
+	if err := s.checkPrecondition(cond); err != nil {
+		return Album{}, err
+	}
+	fields := map[string]any{"title": album.Title, "artist": album.Artist}
+	if album.ArtistID != nil {
+		fields["artist_id"] = *album.ArtistID
+	}
+	return s.updateFields(album.ID, cond, fields, ActionUpdate, actorID, nil)
+}
+
+// Patch applies a JSON Merge Patch or JSON Patch to the album and persists
+// only the columns it changed.
+func (s *service) Patch(id uint, patch []byte, contentType string, cond IfMatch, actorID uint) (Album, error) {
+	if err := s.checkPrecondition(cond); err != nil {
+		return Album{}, err
+	}
+	album, err := s.repo.FindById(id)
 	if err != nil {
 		return Album{}, err
 	}
-	if exit.ID == 0 {
-		return Album{}, gorm.ErrRecordNotFound
+	changes, err := applyPatch(album, patch, contentType)
+	if err != nil {
+		return Album{}, err
+	}
+	if len(changes) == 0 {
+		if !cond.matches(album.Version) {
+			return Album{}, ErrVersionMismatch
+		}
+		return album, nil
+	}
+	return s.updateFields(id, cond, changes, ActionUpdate, actorID, nil)
+}
+
+// updateFields writes fields under the If-Match guard, applies also (if
+// not nil) in the same transaction and records the revision.
+func (s *service) updateFields(id uint, cond IfMatch, fields map[string]any, action string, actorID uint, also func(repo Repository) error) (Album, error) {
+	if err := s.resolveArtistFields(fields); err != nil {
+		return Album{}, err
+	}
+
+	var updated Album
+	err := s.repo.Transaction(func(repo Repository) error {
+		before, err := repo.FindById(id)
+		if err != nil {
+			return err
+		}
+		if updated, err = repo.UpdateFields(id, cond.expectedVersions(), fields); err != nil {
+			return err
+		}
+		if also != nil {
+			if err := also(repo); err != nil {
+				return err
+			}
+			if updated, err = repo.FindById(id); err != nil {
+				return err
+			}
+		}
+		return repo.CreateRevision(Revision{
+			AlbumID: id, Action: action, ActorID: actorID,
+			Before: snapshotOf(before), After: snapshotOf(updated),
+		})
+	})
+	return updated, err
+}
+
+func (s *service) Delete(id uint, cond IfMatch, actorID uint) error {
+	if err := s.checkPrecondition(cond); err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(repo Repository) error {
+		before, err := repo.FindById(id)
+		if err != nil {
+			return err
+		}
+		if err := repo.Delete(id, cond.expectedVersions()); err != nil {
+			return err
+		}
+		return repo.CreateRevision(Revision{
+			AlbumID: id, Action: ActionDelete, ActorID: actorID,
+			Before: snapshotOf(before),
+		})
+	})
+}
+
+func (s *service) FindTrashed(limit, offset int) ([]Album, int64, error) {
+	return s.repo.FindTrashed(limit, offset)
+}
+
+func (s *service) Restore(id uint, actorID uint) (Album, error) {
+	var restored Album
+	err := s.repo.Transaction(func(repo Repository) error {
+		var err error
+		if restored, err = repo.Restore(id); err != nil {
+			return err
+		}
+		return repo.CreateRevision(Revision{
+			AlbumID: id, Action: ActionRestore, ActorID: actorID,
+			After: snapshotOf(restored),
+		})
+	})
+	return restored, err
+}
+
+// Purge permanently removes an album, whether it is live or in the trash.
+func (s *service) Purge(id uint, cond IfMatch, actorID uint) error {
+	if err := s.checkPrecondition(cond); err != nil {
+		return err
+	}
+	err := s.repo.Transaction(func(repo Repository) error {
+		before, err := repo.FindByIdUnscoped(id)
+		if err != nil {
+			return err
+		}
+		if err := repo.HardDelete(id, cond.expectedVersions()); err != nil {
+			return err
+		}
+		return repo.CreateRevision(Revision{
+			AlbumID: id, Action: ActionPurge, ActorID: actorID,
+			Before: snapshotOf(before),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	s.removeBlobs(coverPrefix(id))
+	return nil
+}
+
+// FindRevisions returns an album's history, newest first, with field-level diffs.
+func (s *service) FindRevisions(albumID uint, limit, offset int) ([]RevisionWithDiff, int64, error) {
+	revisions, total, err := s.repo.FindRevisions(albumID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]RevisionWithDiff, len(revisions))
+	for i, rev := range revisions {
+		result[i] = RevisionWithDiff{Revision: rev, Changes: diffSnapshots(rev.Before, rev.After)}
+	}
+	return result, total, nil
+}
+
+// Rollback restores the album's fields to the state recorded after revID.
+// The rollback itself is a new revision, so it can be undone in turn.
+func (s *service) Rollback(albumID, revID uint, cond IfMatch, actorID uint) (Album, error) {
+	if err := s.checkPrecondition(cond); err != nil {
+		return Album{}, err
+	}
+	rev, err := s.repo.FindRevision(albumID, revID)
+	if err != nil {
+		return Album{}, err
+	}
+	if rev.After == nil {
+		return Album{}, ErrRevisionNotRestorable
+	}
+	fields := map[string]any{"title": rev.After.Title, "artist": rev.After.Artist}
+	if rev.After.ArtistID != nil {
+		fields["artist_id"] = *rev.After.ArtistID
+	}
+
+	// Genres and tags are restored too, unless the revision predates them
+	var genreIDs []uint
+	if rev.After.Genres != nil {
+		if genreIDs, err = s.genreIDs(rev.After.Genres); err != nil {
+			return Album{}, err
+		}
+	}
+	return s.updateFields(albumID, cond, fields, ActionRollback, actorID, func(repo Repository) error {
+		if rev.After.Genres != nil {
+			if err := repo.ReplaceGenres(albumID, genreIDs); err != nil {
+				return err
+			}
+		}
+		if rev.After.Tags == nil {
+			return nil
+		}
+		return replaceTagNames(repo, albumID, rev.After.Tags)
+	})
+}
+
+// FindByArtist lists the albums credited to an artist.
+func (s *service) FindByArtist(artistID uint, opts QueryOptions) ([]Album, int64, error) {
+	if _, err := s.artists.FindById(artistID); err != nil {
+		return nil, 0, err
+	}
+	opts.ArtistID = artistID
+	return s.repo.FindAll(opts)
+}
+
+// SetGenres replaces an album's genres. Every slug must name an existing
+// genre. The album's version is bumped so cached copies are invalidated.
+func (s *service) SetGenres(albumID uint, slugs []string, cond IfMatch, actorID uint) (Album, error) {
+	if err := s.checkPrecondition(cond); err != nil {
+		return Album{}, err
+	}
+
+	ids, err := s.genreIDs(slugs)
+	if err != nil {
+		return Album{}, err
+	}
+
+	return s.classify(albumID, cond, actorID, func(repo Repository) error {
+		return repo.ReplaceGenres(albumID, ids)
+	})
+}
+
+// SetTags replaces an album's tags, creating tags that are new.
+func (s *service) SetTags(albumID uint, tags []string, cond IfMatch, actorID uint) (Album, error) {
+	if err := s.checkPrecondition(cond); err != nil {
+		return Album{}, err
+	}
+	names, err := normalizeTags(tags)
+	if err != nil {
+		return Album{}, err
+	}
+
+	return s.classify(albumID, cond, actorID, func(repo Repository) error {
+		return replaceTagNames(repo, albumID, names)
+	})
+}
+
+// genreIDs resolves genre names or slugs to genre IDs, rejecting unknown ones.
+func (s *service) genreIDs(slugs []string) ([]uint, error) {
+	wanted := make([]string, len(slugs))
+	for i, slug := range slugs {
+		wanted[i] = genres.Slugify(slug)
+	}
+	found, err := s.genres.FindBySlugs(wanted)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]uint, len(found))
+	for _, g := range found {
+		known[g.Slug] = g.ID
+	}
+	ids := make([]uint, 0, len(found))
+	for _, slug := range wanted {
+		id, ok := known[slug]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown genre %q", ErrInvalidAlbum, slug)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *service) Facets(opts QueryOptions) (Facets, error) {
+	return s.repo.Facets(opts)
+}
+
+// Export streams every album matching the filters in opts to fn in batches
+// of ExportBatchSize, so memory use does not grow with the catalogue.
+func (s *service) Export(opts QueryOptions, fn func(batch []Album) error) error {
+	return s.repo.FindInBatches(opts, ExportBatchSize, fn)
+}
+
+// classify bumps the album's version under the If-Match guard, applies
+// replace in the same transaction, records the revision and returns the
+// reloaded album.
+func (s *service) classify(albumID uint, cond IfMatch, actorID uint, replace func(repo Repository) error) (Album, error) {
+	var updated Album
+	err := s.repo.Transaction(func(repo Repository) error {
+		before, err := repo.FindById(albumID)
+		if err != nil {
+			return err
+		}
+		if _, err := repo.UpdateFields(albumID, cond.expectedVersions(), nil); err != nil {
+			return err
+		}
+		if err := replace(repo); err != nil {
+			return err
+		}
+		if updated, err = repo.FindById(albumID); err != nil {
+			return err
+		}
+		return repo.CreateRevision(Revision{
+			AlbumID: albumID, Action: ActionUpdate, ActorID: actorID,
+			Before: snapshotOf(before), After: snapshotOf(updated),
+		})
+	})
+	return updated, err
+}
+
+// creditArtist resolves the artist an album is credited to, preferring an
+// explicit artist ID over the legacy free-text name.
+func (s *service) creditArtist(artistID *uint, name string) (artists.Artist, error) {
+	return resolveArtist(s.artists, artistID, name)
+}
+
+// resolveArtist is creditArtist against an explicit resolver.
+func resolveArtist(resolver ArtistResolver, artistID *uint, name string) (artists.Artist, error) {
+	if artistID != nil {
+		artist, err := resolver.FindById(*artistID)
+		if errors.Is(err, dberr.ErrNotFound) {
+			return artists.Artist{}, fmt.Errorf("%w: artist %d does not exist", ErrInvalidAlbum, *artistID)
+		}
+		return artist, err
+	}
+	artist, err := resolver.FindOrCreateByName(name)
+	if errors.Is(err, artists.ErrInvalidName) {
+		return artists.Artist{}, fmt.Errorf("%w: %v", ErrInvalidAlbum, err)
+	}
+	return artist, err
+}
+
+// resolveArtistFields rewrites the artist/artist_id entries of an update so
+// both always point at the same, canonical artist.
+func (s *service) resolveArtistFields(fields map[string]any) error {
+	var (
+		artistID *uint
+		name     string
+	)
+	if id, ok := fields["artist_id"].(uint); ok {
+		artistID = &id
+	} else if n, ok := fields["artist"].(string); ok {
+		name = n
+	} else {
+		return nil
+	}
+
+	artist, err := s.creditArtist(artistID, name)
+	if err != nil {
+		return err
 	}
-	return s.repo.Update(album)
+	fields["artist_id"], fields["artist"] = artist.ID, artist.Name
+	return nil
 }
 
-func (s *service) Delete(id uint) error {
-	return s.repo.Delete(id)
+// PurgeExpired permanently removes albums that have been in the trash longer
+// than the configured retention. A zero retention keeps trashed albums forever.
+func (s *service) PurgeExpired() (int64, error) {
+	if s.cfg.Albums.TrashRetention <= 0 {
+		return 0, nil
+	}
+	ids, err := s.repo.PurgeDeletedBefore(time.Now().Add(-s.cfg.Albums.TrashRetention))
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		s.removeBlobs(coverPrefix(id))
+	}
+	return int64(len(ids)), nil
+}
+
+// UploadCover validates an image, stores it with its thumbnails and makes it
+// the album's cover. The previous cover's blobs are removed afterwards.
+func (s *service) UploadCover(albumID uint, image io.Reader, cond IfMatch, actorID uint) (Album, error) {
+	if err := s.checkPrecondition(cond); err != nil {
+		return Album{}, err
+	}
+
+	// 1. Read at most one byte past the limit to detect oversized uploads
+	data, err := io.ReadAll(io.LimitReader(image, s.cfg.Covers.MaxBytes+1))
+	if err != nil {
+		return Album{}, err
+	}
+	if int64(len(data)) > s.cfg.Covers.MaxBytes {
+		return Album{}, ErrCoverTooLarge
+	}
+	cover, err := processCover(data, s.cfg.Covers.ThumbnailSizes)
+	if err != nil {
+		return Album{}, err
+	}
+
+	// 2. Store the blobs, but only for albums that exist
+	before, err := s.repo.FindById(albumID)
+	if err != nil {
+		return Album{}, err
+	}
+	newPrefix := coverPrefix(albumID) + "/" + cover.hash
+	if err := s.storeCover(albumID, cover); err != nil {
+		if before.CoverHash != cover.hash {
+			s.removeBlobs(newPrefix)
+		}
+		return Album{}, err
+	}
+
+	// 3. Point the album at the new cover under the If-Match guard, recording a revision
+	updated, err := s.updateFields(albumID, cond, map[string]any{
+		"cover_hash": cover.hash,
+		"cover_type": cover.contentType,
+	}, ActionUpdate, actorID, nil)
+	if err != nil {
+		if before.CoverHash != cover.hash {
+			s.removeBlobs(newPrefix)
+		}
+		return Album{}, err
+	}
+	if before.CoverHash != "" && before.CoverHash != cover.hash {
+		s.removeBlobs(coverPrefix(albumID) + "/" + before.CoverHash)
+	}
+	return updated, nil
+}
+
+// OpenCover opens one rendition of an album's cover: "original" or one of
+// the configured thumbnail sizes.
+func (s *service) OpenCover(albumID uint, rendition string) (CoverBlob, error) {
+	album, err := s.repo.FindById(albumID)
+	if err != nil {
+		return CoverBlob{}, err
+	}
+	if album.CoverHash == "" {
+		return CoverBlob{}, ErrNoCover
+	}
+
+	contentType := album.CoverType
+	if rendition != CoverOriginal {
+		size, err := strconv.Atoi(rendition)
+		if err != nil || !slices.Contains(s.cfg.Covers.ThumbnailSizes, size) {
+			return CoverBlob{}, ErrNoCover
+		}
+		contentType = "image/jpeg"
+	}
+
+	body, info, err := s.blobs.Get(coverKey(albumID, album.CoverHash, rendition))
+	if errors.Is(err, storage.ErrNotFound) {
+		return CoverBlob{}, ErrNoCover
+	}
+	if err != nil {
+		return CoverBlob{}, err
+	}
+	return CoverBlob{
+		Body:        body,
+		Size:        info.Size,
+		ContentType: contentType,
+		ETag:        fmt.Sprintf(`"%s-%s"`, album.CoverHash, rendition),
+		ModTime:     info.ModTime,
+	}, nil
+}
+
+// storeCover writes the original image and every thumbnail of cover.
+func (s *service) storeCover(albumID uint, cover coverImage) error {
+	if err := s.blobs.Put(coverKey(albumID, cover.hash, CoverOriginal), bytes.NewReader(cover.original)); err != nil {
+		return err
+	}
+	for size, data := range cover.thumbnails {
+		if err := s.blobs.Put(coverKey(albumID, cover.hash, strconv.Itoa(size)), bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeBlobs deletes blobs under prefix. Failures only leave orphaned files
+// behind, so they are logged rather than failing the request.
+func (s *service) removeBlobs(prefix string) {
+	if err := s.blobs.DeletePrefix(prefix); err != nil {
+		log.Printf("⚠️ failed to remove blobs under %s: %v", prefix, err)
+	}
+}
+
+func (s *service) FindByIdFields(id uint, fs Fieldset) (Album, error) {
+	return s.repo.FindByIdFields(id, fs)
+}
+
+func (s *service) FindTracks(albumID uint) ([]Track, error) {
+	if _, err := s.repo.FindById(albumID); err != nil {
+		return nil, err
+	}
+	return s.repo.FindTracks(albumID)
+}
+
+// CreateTrack adds a track to an album. Without an explicit position the
+// track is appended to the end of its disc.
+func (s *service) CreateTrack(albumID uint, track Track) (Track, error) {
+	if _, err := s.repo.FindById(albumID); err != nil {
+		return Track{}, err
+	}
+	track.ID, track.AlbumID = 0, albumID
+	if err := track.normalize(); err != nil {
+		return Track{}, err
+	}
+	if track.Position == 0 {
+		next, err := s.repo.NextTrackPosition(albumID, track.DiscNumber)
+		if err != nil {
+			return Track{}, err
+		}
+		track.Position = next
+	}
+	return s.repo.CreateTrack(track)
+}
+
+func (s *service) UpdateTrack(albumID, trackID uint, track Track) (Track, error) {
+	existing, err := s.repo.FindTrack(albumID, trackID)
+	if err != nil {
+		return Track{}, err
+	}
+	track.ID, track.AlbumID = trackID, albumID
+	if track.Position == 0 {
+		track.Position = existing.Position
+	}
+	if err := track.normalize(); err != nil {
+		return Track{}, err
+	}
+	return s.repo.UpdateTrack(track)
+}
+
+func (s *service) DeleteTrack(albumID, trackID uint) error {
+	return s.repo.DeleteTrack(albumID, trackID)
+}
+
+// ReorderTracks renumbers the album's tracks in the order given, counting
+// positions from 1 on each disc.
+func (s *service) ReorderTracks(albumID uint, order TrackOrder) ([]Track, error) {
+	tracks, err := s.FindTracks(albumID)
+	if err != nil {
+		return nil, err
+	}
+	if len(order.TrackIDs) != len(tracks) {
+		return nil, ErrInvalidTrackOrder
+	}
+
+	discs := make(map[uint]uint, len(tracks))
+	for _, t := range tracks {
+		discs[t.ID] = t.DiscNumber
+	}
+	positions := make(map[uint]uint, len(tracks))
+	next := map[uint]uint{}
+	for _, id := range order.TrackIDs {
+		disc, ok := discs[id]
+		if _, dup := positions[id]; !ok || dup {
+			return nil, ErrInvalidTrackOrder
+		}
+		next[disc]++
+		positions[id] = next[disc]
+	}
+
+	if err := s.repo.SetTrackPositions(albumID, positions); err != nil {
+		return nil, err
+	}
+	return s.repo.FindTracks(albumID)
+}
+
+// checkPrecondition enforces the configured If-Match strictness. A header that
+// lists no usable entity tag can never match, so it fails straight away.
+func (s *service) checkPrecondition(cond IfMatch) error {
+	if !cond.Present {
+		if s.cfg.Albums.RequireIfMatch {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+	if !cond.Any && len(cond.Versions) == 0 {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// errImportRollback rolls back an import transaction without failing the import.
+var errImportRollback = errors.New("import rolled back")
+
+// Import reads albums from src and creates, updates or skips each one
+// according to opts. Row problems are collected in the report; only
+// unexpected failures are returned as errors.
+func (s *service) Import(src ImportSource, opts ImportOptions, actorID uint) (ImportReport, error) {
+	report := ImportReport{DryRun: opts.DryRun, Atomic: opts.Atomic, Errors: []ImportError{}}
+
+	// A dry run always uses a single transaction so it can be rolled back whole.
+	batchSize := ImportBatchSize
+	if opts.Atomic || opts.DryRun {
+		batchSize = 0
+	}
+
+	for done := false; !done; {
+		var tally importTally
+		err := s.repo.Transaction(func(repo Repository) error {
+			for n := 0; batchSize == 0 || n < batchSize; n++ {
+				row, err := src.Next()
+				if err == io.EOF {
+					done = true
+					break
+				}
+				if err == nil {
+					err = s.importRow(repo, row, opts, actorID, &tally)
+				}
+				var rowErr *ImportError
+				if errors.As(err, &rowErr) {
+					report.Rows++
+					report.addError(*rowErr)
+					if rowErr.fatal {
+						done = true
+						break
+					}
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				report.Rows++
+			}
+			if opts.DryRun || (opts.Atomic && report.Failed > 0) {
+				return errImportRollback
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errImportRollback) {
+			return report, err
+		}
+		report.Created += tally.created
+		report.Updated += tally.updated
+		report.Skipped += tally.skipped
+		if err == nil {
+			report.Committed = true
+		}
+	}
+	return report, nil
+}
+
+// importRow validates one row and writes it through repo. Validation
+// problems are returned as *ImportError.
+func (s *service) importRow(repo Repository, row ImportRow, opts ImportOptions, actorID uint, tally *importTally) error {
+	rowError := func(field string, err error) error {
+		msg := strings.TrimPrefix(err.Error(), ErrInvalidAlbum.Error()+": ")
+		return &ImportError{Line: row.Line, Field: field, Message: msg}
+	}
+
+	// 1. Validate the row and resolve its artist, genres and tags
+	if row.Title == "" {
+		return &ImportError{Line: row.Line, Field: "title", Message: "title is required"}
+	}
+	if row.ArtistID == nil && row.Artist == "" {
+		return &ImportError{Line: row.Line, Field: "artist", Message: "artist or artist_id is required"}
+	}
+	artist, err := resolveArtist(artists.NewService(repo.Artists()), row.ArtistID, row.Artist)
+	if errors.Is(err, ErrInvalidAlbum) {
+		return rowError("artist", err)
+	}
+	if err != nil {
+		return err
+	}
+	genreIDs, err := s.genreIDs(row.Genres)
+	if errors.Is(err, ErrInvalidAlbum) {
+		return rowError("genres", err)
+	}
+	if err != nil {
+		return err
+	}
+	tags, err := normalizeTags(row.Tags)
+	if err != nil {
+		return rowError("tags", err)
+	}
+
+	// 2. Decide between create, update and skip
+	existing, err := repo.FindDuplicate(row.Title, artist.ID)
+	switch {
+	case errors.Is(err, dberr.ErrNotFound):
+		album := Album{Title: row.Title, Artist: artist.Name, ArtistID: &artist.ID, Version: 1}
+		created, err := repo.Create(album)
+		if err != nil {
+			return err
+		}
+		if err := s.importClassification(repo, created.ID, row, genreIDs, tags); err != nil {
+			return err
+		}
+		if created, err = repo.FindById(created.ID); err != nil {
+			return err
+		}
+		tally.created++
+		return repo.CreateRevision(Revision{
+			AlbumID: created.ID, Action: ActionCreate, ActorID: actorID,
+			After: snapshotOf(created),
+		})
+	case err != nil:
+		return err
+	case opts.OnDuplicate == OnDuplicateSkip:
+		tally.skipped++
+		return nil
+	case opts.OnDuplicate == OnDuplicateFail:
+		return &ImportError{Line: row.Line, Field: "title", Message: fmt.Sprintf("duplicate of album %d", existing.ID)}
+	}
+
+	before, err := repo.FindById(existing.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := repo.UpdateFields(existing.ID, nil, map[string]any{
+		"title":     row.Title,
+		"artist":    artist.Name,
+		"artist_id": artist.ID,
+	}); err != nil {
+		return err
+	}
+	if err := s.importClassification(repo, existing.ID, row, genreIDs, tags); err != nil {
+		return err
+	}
+	updated, err := repo.FindById(existing.ID)
+	if err != nil {
+		return err
+	}
+	tally.updated++
+	return repo.CreateRevision(Revision{
+		AlbumID: existing.ID, Action: ActionUpdate, ActorID: actorID,
+		Before: snapshotOf(before), After: snapshotOf(updated),
+	})
+}
+
+// importClassification replaces an imported album's genres and tags with
+// the row's, leaving them alone when the row has none.
+func (s *service) importClassification(repo Repository, albumID uint, row ImportRow, genreIDs []uint, tags []string) error {
+	if len(row.Genres) > 0 {
+		if err := repo.ReplaceGenres(albumID, genreIDs); err != nil {
+			return err
+		}
+	}
+	if len(row.Tags) > 0 {
+		return replaceTagNames(repo, albumID, tags)
+	}
+	return nil
+}
+
+// replaceTagNames sets the album's tags to the normalized names, creating
+// tags that are new.
+func replaceTagNames(repo Repository, albumID uint, names []string) error {
+	stored, err := repo.UpsertTags(names)
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, 0, len(stored))
+	for _, t := range stored {
+		ids = append(ids, t.ID)
+	}
+	return repo.ReplaceTags(albumID, ids)
 }
@@ -0,0 +1,90 @@
+package albums
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidTrack is returned when a track fails validation.
+	ErrInvalidTrack = errors.New("invalid track")
+	// ErrInvalidTrackOrder is returned when a reorder request does not list
+	// every track of the album exactly once.
+	ErrInvalidTrackOrder = errors.New("track order must list every track of the album exactly once")
+)
+
+// isrcPattern matches a normalised ISRC: country, registrant, year, designation.
+var isrcPattern = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}[0-9]{7}$`)
+
+// Track is a single song on an album.
+type Track struct {
+	AlbumID    uint   `json:"album_id" gorm:"not null;index"`
+	DiscNumber uint   `json:"disc_number" gorm:"not null;default:1"`
+	Position   uint   `json:"position" gorm:"not null"`
+	Title      string `json:"title" gorm:"not null"`
+	Duration   uint   `json:"duration"` // in seconds
+	ISRC       string `json:"isrc" gorm:"column:isrc"`
+	gorm.Model
+}
+
+// trackColumns are the CSV columns of a track listing.
+var trackColumns = []string{"id", "album_id", "disc_number", "position", "title", "duration", "isrc"}
+
+// CSVHeader and CSVRecord let track listings be served as text/csv.
+func (TrackResponse) CSVHeader() []string {
+	return slices.Clone(trackColumns)
+}
+
+func (t TrackResponse) CSVRecord() []string {
+	return []string{
+		strconv.FormatUint(uint64(t.ID), 10),
+		strconv.FormatUint(uint64(t.AlbumID), 10),
+		strconv.FormatUint(uint64(t.DiscNumber), 10),
+		strconv.FormatUint(uint64(t.Position), 10),
+		t.Title,
+		strconv.FormatUint(uint64(t.Duration), 10),
+		t.ISRC,
+	}
+}
+
+// TrackOrder is the body of a bulk reorder request: every track ID of the
+// album in the desired order. Positions are renumbered per disc.
+type TrackOrder struct {
+	TrackIDs []uint `json:"track_ids" binding:"required"`
+}
+
+// normalize fills defaults and canonicalises the ISRC, then validates the track.
+func (t *Track) normalize() error {
+	if t.DiscNumber == 0 {
+		t.DiscNumber = 1
+	}
+	t.Title = strings.TrimSpace(t.Title)
+	if t.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidTrack)
+	}
+	t.ISRC = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(t.ISRC), "-", ""))
+	if t.ISRC != "" && !isrcPattern.MatchString(t.ISRC) {
+		return fmt.Errorf("%w: isrc must look like CC-XXX-YY-NNNNN", ErrInvalidTrack)
+	}
+	return nil
+}
+
+// TotalRuntime sums the durations of tracks, in seconds.
+func TotalRuntime(tracks []Track) uint {
+	var total uint
+	for _, t := range tracks {
+		total += t.Duration
+	}
+	return total
+}
+
+// orderTracks applies the scope that lists tracks in playing order.
+func orderTracks(tx *gorm.DB) *gorm.DB {
+	return tx.Order("disc_number ASC").Order("position ASC").Order("id ASC")
+}
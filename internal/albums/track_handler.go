@@ -0,0 +1,179 @@
+package albums
+
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+	"gin-quickstart/internal/negotiate"
+	"gin-quickstart/internal/problem"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTracks lists an album's tracks in playing order with the total runtime.
+func (h *Handler) GetTracks(c *gin.Context) {
+	// 1. Convert string URL param to uint
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Call service to load the tracks
+	tracks, err := h.service.FindTracks(uint(albumID))
+	if err != nil {
+		respondTrackError(c, err)
+		return
+	}
+
+	// 3. Return tracks with total runtime
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"tracks":        NewTrackResponses(tracks),
+			"total_runtime": TotalRuntime(tracks),
+		},
+		"message": "Tracks retrieved successfully",
+	})
+}
+
+// CreateTrack adds a track to an album.
+func (h *Handler) CreateTrack(c *gin.Context) {
+	var req TrackRequest
+
+	// 1. Convert string URL param to uint
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Bind the request body to the track request
+	if err := negotiate.Bind(c, &req); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
+		return
+	}
+
+	// 3. Call service to create the track
+	created, err := h.service.CreateTrack(uint(albumID), req.toTrack())
+	if err != nil {
+		respondTrackError(c, err)
+		return
+	}
+
+	// 4. Return created track with 201 Created status
+	negotiate.Render(c, http.StatusCreated, gin.H{
+		"data": gin.H{
+			"track": NewTrackResponse(created),
+		},
+		"message": "Track created successfully",
+	})
+}
+
+// UpdateTrack replaces a track's details.
+func (h *Handler) UpdateTrack(c *gin.Context) {
+	var req TrackRequest
+
+	// 1. Convert string URL params to uint
+	albumID, trackID, ok := parseTrackParams(c)
+	if !ok {
+		return
+	}
+
+	// 2. Bind the request body to the track request
+	if err := negotiate.Bind(c, &req); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
+		return
+	}
+
+	// 3. Call service to update the track
+	updated, err := h.service.UpdateTrack(albumID, trackID, req.toTrack())
+	if err != nil {
+		respondTrackError(c, err)
+		return
+	}
+
+	// 4. Return updated track
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"track": NewTrackResponse(updated),
+		},
+		"message": "Track updated successfully",
+	})
+}
+
+// DeleteTrack removes a track from an album.
+func (h *Handler) DeleteTrack(c *gin.Context) {
+	// 1. Convert string URL params to uint
+	albumID, trackID, ok := parseTrackParams(c)
+	if !ok {
+		return
+	}
+
+	// 2. Call service to delete the track
+	if err := h.service.DeleteTrack(albumID, trackID); err != nil {
+		respondTrackError(c, err)
+		return
+	}
+
+	// 3. Return no content status
+	c.Status(http.StatusNoContent)
+}
+
+// ReorderTracks renumbers all of an album's tracks in one request.
+func (h *Handler) ReorderTracks(c *gin.Context) {
+	var order TrackOrder
+
+	// 1. Convert string URL param to uint
+	albumID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Bind the request body to the new order
+	if err := negotiate.Bind(c, &order); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
+		return
+	}
+
+	// 3. Call service to apply the order
+	tracks, err := h.service.ReorderTracks(uint(albumID), order)
+	if err != nil {
+		respondTrackError(c, err)
+		return
+	}
+
+	// 4. Return the reordered tracks
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data": gin.H{
+			"tracks":        NewTrackResponses(tracks),
+			"total_runtime": TotalRuntime(tracks),
+		},
+		"message": "Tracks reordered successfully",
+	})
+}
+
+// parseTrackParams reads :id and :trackId, answering 400 if either is malformed.
+func parseTrackParams(c *gin.Context) (albumID, trackID uint, ok bool) {
+	a, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return 0, 0, false
+	}
+	t, err := strconv.ParseUint(c.Param("trackId"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid track ID format. Must be an integer."))
+		return 0, 0, false
+	}
+	return uint(a), uint(t), true
+}
+
+// respondTrackError reports a failed track request; a missing record may
+// be the album or the track.
+func respondTrackError(c *gin.Context, err error) {
+	if errors.Is(err, dberr.ErrNotFound) {
+		err = problem.New(http.StatusNotFound, "album or track not found")
+	}
+	c.Error(err)
+}
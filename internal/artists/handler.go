@@ -0,0 +1,189 @@
+package artists
+
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/problem"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Handler holds the necessary dependencies for the artist handlers.
+type Handler struct {
+	service Service
+}
+
+// NewHandler is the constructor for Handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RegisterRoutes attaches artist routes to the Gin engine.
+func (h *Handler) RegisterRoutes(g *gin.RouterGroup) {
+	artistGroup := g.Group("/artists")
+	{
+		// 1. READ routes (accessible to anyone with a valid token: 'user' or 'admin')
+		artistGroup.GET("/", h.GetArtists)
+		artistGroup.GET("/:id", h.GetArtistByID)
+
+		// 2. WRITE routes (only accessible to 'admin')
+		adminArtistGroup := artistGroup.Group("/")
+		adminArtistGroup.Use(middleware.Authorize("admin"))
+		{
+			adminArtistGroup.POST("/", h.CreateArtist)
+			adminArtistGroup.PUT("/:id", h.UpdateArtist)
+		}
+	}
+}
+
+// GetArtists lists artists alphabetically, one page at a time.
+func (h *Handler) GetArtists(c *gin.Context) {
+	// 1. Parse pagination
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.Error(problem.New(http.StatusBadRequest, "page must be an integer >= 1"))
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxPageSize {
+		c.Error(problem.New(http.StatusBadRequest, "page_size must be an integer between 1 and 100"))
+		return
+	}
+
+	// 2. Call service to get the page
+	artists, total, err := h.service.FindAll(pageSize, (page-1)*pageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 3. Return artists with pagination meta
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"artists": artists,
+		},
+		"meta": gin.H{
+			"total":     total,
+			"page":      page,
+			"page_size": pageSize,
+		},
+		"message": "Artists retrieved successfully",
+	})
+}
+
+// GetArtistByID retrieves a single artist by its ID.
+func (h *Handler) GetArtistByID(c *gin.Context) {
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Call service to find by ID
+	artist, err := h.service.FindById(uint(idUint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 3. Return found artist
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"artist": artist,
+		},
+		"message": "Artist retrieved successfully",
+	})
+}
+
+// CreateArtist processes a POST request to add a new artist.
+func (h *Handler) CreateArtist(c *gin.Context) {
+	var artist Artist
+
+	// 1. Bind JSON body to artist struct
+	if err := c.ShouldBindJSON(&artist); err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 2. Call service to create artist
+	created, err := h.service.Create(artist)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 3. Return created artist with 201 Created status
+	c.JSON(http.StatusCreated, gin.H{
+		"data": gin.H{
+			"artist": created,
+		},
+		"message": "Artist created successfully",
+	})
+}
+
+// UpdateArtist renames an artist; albums credited to it follow the new name.
+func (h *Handler) UpdateArtist(c *gin.Context) {
+	var artist Artist
+
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Bind JSON body to artist struct
+	if err := c.ShouldBindJSON(&artist); err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+	artist.ID = uint(idUint)
+
+	// 3. Call service to update
+	updated, err := h.service.Update(artist, actorID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 4. Return updated artist
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"artist": updated,
+		},
+		"message": "Artist updated successfully",
+	})
+}
+
+// actorID returns the ID of the authenticated user making the request.
+func actorID(c *gin.Context) uint {
+	if claims, ok := middleware.CurrentClaims(c); ok {
+		return claims.ID
+	}
+	return 0
+}
+
+// ErrorStatuses maps the artist domain errors to HTTP statuses for
+// middleware.Problems.
+var ErrorStatuses = []problem.Mapping{
+	{Err: ErrInvalidName, Status: http.StatusUnprocessableEntity},
+	{Err: ErrDuplicateArtist, Status: http.StatusConflict},
+}
+
+// respondError reports a failed artist request; a missing record is named
+// as the artist.
+func respondError(c *gin.Context, err error) {
+	if errors.Is(err, dberr.ErrNotFound) {
+		err = problem.New(http.StatusNotFound, "artist not found")
+	}
+	c.Error(err)
+}
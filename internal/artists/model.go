@@ -0,0 +1,30 @@
+package artists
+
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+type Artist struct {
+	Name string `json:"name" binding:"required"`
+	// NormalizedName is the deduplication key, so "The Beatles" and
+	// "beatles" resolve to the same artist.
+	NormalizedName string `json:"-" gorm:"uniqueIndex;not null"`
+	gorm.Model
+}
+
+// NormalizeName folds an artist name to its deduplication key: lower case,
+// "&" spelled as "and", a leading "the" dropped, punctuation removed and
+// whitespace collapsed.
+func NormalizeName(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "&", " and "))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 1 && words[0] == "the" {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
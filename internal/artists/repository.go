@@ -0,0 +1,115 @@
+package artists
+
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository defines the interface for data access methods.
+type Repository interface {
+	FindAll(limit, offset int) ([]Artist, int64, error)
+	FindById(id uint) (Artist, error)
+	FindByNormalizedName(normalized string) (Artist, error)
+	Create(artist Artist) (Artist, error)
+	CreateIfMissing(artist Artist) (Artist, error)
+	Update(artist Artist, actorID uint) (Artist, error)
+}
+
+// RenameHook runs inside the transaction that renames an artist, so data
+// copied from the artist's name can follow the rename atomically.
+type RenameHook func(tx *gorm.DB, artist Artist, actorID uint) error
+
+// repository is the concrete implementation of the Repository interface.
+type repository struct {
+	DB       *gorm.DB
+	onRename []RenameHook
+}
+
+// NewRepository is the constructor for the concrete repository. The hooks
+// run whenever Update renames an artist.
+func NewRepository(db *gorm.DB, onRename ...RenameHook) Repository {
+	return &repository{DB: db, onRename: onRename}
+}
+
+func (r *repository) FindAll(limit, offset int) ([]Artist, int64, error) {
+	var (
+		artists []Artist
+		total   int64
+	)
+	if err := r.DB.Model(&Artist{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := r.DB.Order("name ASC").Order("id ASC").Limit(limit).Offset(offset).Find(&artists).Error; err != nil {
+		return nil, 0, err
+	}
+	return artists, total, nil
+}
+
+func (r *repository) FindById(id uint) (Artist, error) {
+	var artist Artist
+	if err := r.DB.First(&artist, "id = ?", id).Error; err != nil {
+		return Artist{}, err
+	}
+	return artist, nil
+}
+
+func (r *repository) FindByNormalizedName(normalized string) (Artist, error) {
+	var artist Artist
+	if err := r.DB.First(&artist, "normalized_name = ?", normalized).Error; err != nil {
+		return Artist{}, err
+	}
+	return artist, nil
+}
+
+func (r *repository) Create(artist Artist) (Artist, error) {
+	if err := r.DB.Create(&artist).Error; err != nil {
+		// The unique index on normalized_name catches races ensureUnique misses.
+		if errors.Is(err, dberr.ErrDuplicate) {
+			return Artist{}, ErrDuplicateArtist
+		}
+		return Artist{}, err
+	}
+	return artist, nil
+}
+
+// CreateIfMissing inserts artist unless one with the same normalized name
+// already exists, and returns whichever row is stored. It is safe to call
+// concurrently for the same name.
+func (r *repository) CreateIfMissing(artist Artist) (Artist, error) {
+	err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "normalized_name"}},
+		DoNothing: true,
+	}).Create(&artist).Error
+	if err != nil {
+		return Artist{}, err
+	}
+	return r.FindByNormalizedName(artist.NormalizedName)
+}
+
+func (r *repository) Update(artist Artist, actorID uint) (Artist, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&artist).Select("name", "normalized_name").Updates(artist)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return dberr.ErrNotFound
+		}
+		for _, hook := range r.onRename {
+			if err := hook(tx, artist, actorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, dberr.ErrDuplicate) {
+		return Artist{}, ErrDuplicateArtist
+	}
+	if err != nil {
+		return Artist{}, err
+	}
+	return r.FindById(artist.ID)
+}
@@ -0,0 +1,100 @@
+package artists
+
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+	"strings"
+)
+
+var (
+	// ErrInvalidName is returned for a name with no letters or digits.
+	ErrInvalidName = errors.New("artist name must contain at least one letter or digit")
+	// ErrDuplicateArtist is returned when another artist already has an
+	// equivalent name.
+	ErrDuplicateArtist = errors.New("an artist with an equivalent name already exists")
+)
+
+// Service defines the methods for business logic.
+type Service interface {
+	FindAll(limit, offset int) ([]Artist, int64, error)
+	FindById(id uint) (Artist, error)
+	FindOrCreateByName(name string) (Artist, error)
+	Create(artist Artist) (Artist, error)
+	Update(artist Artist, actorID uint) (Artist, error)
+}
+
+// service is the concrete implementation of Service.
+type service struct {
+	repo Repository
+}
+
+// NewService is the constructor.
+func NewService(r Repository) Service {
+	return &service{repo: r}
+}
+
+func (s *service) FindAll(limit, offset int) ([]Artist, int64, error) {
+	return s.repo.FindAll(limit, offset)
+}
+
+func (s *service) FindById(id uint) (Artist, error) {
+	return s.repo.FindById(id)
+}
+
+// FindOrCreateByName returns the artist whose normalized name matches name,
+// creating it on first use.
+func (s *service) FindOrCreateByName(name string) (Artist, error) {
+	artist, err := prepare(Artist{Name: name})
+	if err != nil {
+		return Artist{}, err
+	}
+	return s.repo.CreateIfMissing(artist)
+}
+
+func (s *service) Create(artist Artist) (Artist, error) {
+	artist, err := prepare(artist)
+	if err != nil {
+		return Artist{}, err
+	}
+	if err := s.ensureUnique(artist); err != nil {
+		return Artist{}, err
+	}
+	artist.ID = 0
+	return s.repo.Create(artist)
+}
+
+func (s *service) Update(artist Artist, actorID uint) (Artist, error) {
+	artist, err := prepare(artist)
+	if err != nil {
+		return Artist{}, err
+	}
+	if err := s.ensureUnique(artist); err != nil {
+		return Artist{}, err
+	}
+	return s.repo.Update(artist, actorID)
+}
+
+// ensureUnique rejects artist if a different artist has the same normalized name.
+func (s *service) ensureUnique(artist Artist) error {
+	existing, err := s.repo.FindByNormalizedName(artist.NormalizedName)
+	if errors.Is(err, dberr.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != artist.ID {
+		return ErrDuplicateArtist
+	}
+	return nil
+}
+
+// prepare trims the display name and derives the normalized name.
+func prepare(artist Artist) (Artist, error) {
+	artist.Name = strings.TrimSpace(artist.Name)
+	artist.NormalizedName = NormalizeName(artist.Name)
+	if artist.NormalizedName == "" {
+		return Artist{}, ErrInvalidName
+	}
+	return artist, nil
+}
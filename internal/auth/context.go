@@ -0,0 +1,17 @@
+package auth
+
+import "github.com/gin-gonic/gin"
+
+// ClaimsContextKey is the gin context key under which
+// middleware.AuthMiddleware stores the verified *Claims.
+const ClaimsContextKey = "claims"
+
+// ContextClaims returns the claims stored by middleware.AuthMiddleware, if any.
+func ContextClaims(c *gin.Context) (*Claims, bool) {
+	claimsRaw, exists := c.Get(ClaimsContextKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := claimsRaw.(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,86 @@
+package auth
+
+import "time"
+
+// RegisterRequest is the body of POST /auth/signup. Accounts get the user
+// role unless InviteCode redeems an invitation to another role.
+type RegisterRequest struct {
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	InviteCode string `json:"invite_code"`
+}
+
+// LoginRequest is the body of POST /auth/login.
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshRequest is the body of POST /auth/refresh.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// InvitationRequest is the body of POST /invitations.
+type InvitationRequest struct {
+	Role string `json:"role" binding:"required,oneof=user admin"`
+}
+
+// InvitationResponse is a newly created invitation. Code is only ever shown
+// in this response.
+type InvitationResponse struct {
+	ID        uint   `json:"id"`
+	Code      string `json:"code"`
+	Role      string `json:"role"`
+	ExpiresAt string `json:"expires_at"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NewInvitationResponse maps an invitation and its code.
+func NewInvitationResponse(inv Invitation, code string) InvitationResponse {
+	return InvitationResponse{
+		ID:        inv.ID,
+		Code:      code,
+		Role:      inv.Role,
+		ExpiresAt: inv.ExpiresAt.UTC().Format(time.RFC3339),
+		CreatedAt: inv.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// UserResponse is a user account as returned by the API. The password hash
+// never leaves the service.
+type UserResponse struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NewUserResponse maps a user.
+func NewUserResponse(user User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		CreatedAt: user.CreatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// TokenResponse carries the tokens issued by POST /auth/login and
+// POST /auth/refresh. Token is the access token.
+type TokenResponse struct {
+	Token        string `json:"token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // in seconds
+	RefreshToken string `json:"refresh_token"`
+}
+
+// NewTokenResponse maps an issued token pair.
+func NewTokenResponse(pair TokenPair) TokenResponse {
+	return TokenResponse{
+		Token:        pair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(pair.ExpiresIn.Seconds()),
+		RefreshToken: pair.RefreshToken,
+	}
+}
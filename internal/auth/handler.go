@@ -2,11 +2,13 @@ package auth
 
 import (
 	"errors"
+	"gin-quickstart/internal/dberr"
+	"gin-quickstart/internal/negotiate"
+	"gin-quickstart/internal/problem"
 	"net/http"
-	"strings"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 type Handler struct {
@@ -19,41 +21,63 @@ func NewHandler(service AuthService) *Handler {
 }
 
 func (h *Handler) RegisterRoutes(g *gin.RouterGroup) {
-	authGroup := g.Group("/auth")
+	authGroup := g.Group("/auth", negotiate.Offer(negotiate.Structured...))
 	{
 		authGroup.POST("/signup", h.SignUp)
 		authGroup.POST("/login", h.Login)
+		authGroup.POST("/refresh", h.Refresh)
+	}
+}
+
+// RegisterWellKnownRoutes registers the discovery documents served at the
+// root of the site.
+func (h *Handler) RegisterWellKnownRoutes(r gin.IRouter) {
+	r.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+// RegisterProtectedRoutes registers the routes that need a verified token.
+// requireAdmin guards the admin routes; it is passed in because middleware
+// depends on this package.
+func (h *Handler) RegisterProtectedRoutes(g *gin.RouterGroup, requireAdmin gin.HandlerFunc) {
+	g.POST("/auth/logout", h.Logout)
+
+	userGroup := g.Group("/users", requireAdmin)
+	{
+		userGroup.DELETE("/:id/sessions", h.RevokeSessions)
+	}
+
+	invitationGroup := g.Group("/invitations", requireAdmin, negotiate.Offer(negotiate.Structured...))
+	{
+		invitationGroup.POST("", h.CreateInvitation)
 	}
 }
 
 // SignUp handles user registration requests.
 func (h *Handler) SignUp(c *gin.Context) {
 	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := negotiate.Bind(c, &req); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
 		return
 	}
 
 	// Call service to register user
 	user, err := h.Service.SignUp(req)
 	if err != nil {
-		// Check for username collision (GORM unique constraint violation)
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") || strings.Contains(err.Error(), "Duplicate entry") {
-			c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"}) // 409 Conflict
+		// Check for username collision (unique index on username)
+		if errors.Is(err, ErrUsernameTaken) {
+			c.Error(problem.New(http.StatusConflict, "Username already taken")) // 409 Conflict
+		} else if errors.Is(err, ErrInvalidInvitation) {
+			c.Error(problem.New(http.StatusUnprocessableEntity, err.Error())) // 422 Unprocessable Entity
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "User registration failed"}) // 500 Internal Server Error
+			c.Error(err) // 500 Internal Server Error
 		}
 		return
 	}
 
 	// Successful registration
-	c.JSON(http.StatusCreated, gin.H{
+	negotiate.Render(c, http.StatusCreated, gin.H{
 		"data": gin.H{
-			"user": gin.H{
-				"id":       user.ID,
-				"username": user.Username,
-				"role":     user.Role,
-			},
+			"user": NewUserResponse(user),
 		},
 		"message": "User registered successfully",
 	})
@@ -62,28 +86,138 @@ func (h *Handler) SignUp(c *gin.Context) {
 // Login handles user authentication and JWT token generation.
 func (h *Handler) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := negotiate.Bind(c, &req); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
 		return
 	}
 
-	// Call service to authenticate user and generate token
-	token, err := h.Service.Login(req)
+	// Call service to authenticate user and generate tokens
+	tokens, err := h.Service.Login(req)
 	if err != nil {
 		// Check specifically for service errors (invalid credentials, user not found)
-		if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"}) // 401 Unauthorized
+		if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, dberr.ErrNotFound) {
+			c.Error(problem.New(http.StatusUnauthorized, "Invalid username or password")) // 401 Unauthorized
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed"}) // 500 Internal Server Error
+		c.Error(err) // 500 Internal Server Error
 		return
 	}
 
 	// Successful login
-	c.JSON(http.StatusOK, gin.H{
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data":    NewTokenResponse(tokens),
+		"message": "Login successful",
+	})
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token; the presented one cannot be used again.
+func (h *Handler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := negotiate.Bind(c, &req); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
+		return
+	}
+
+	// Call service to rotate the refresh token
+	tokens, err := h.Service.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			c.Error(problem.New(http.StatusUnauthorized, err.Error())) // 401 Unauthorized
+			return
+		}
+		c.Error(err) // 500 Internal Server Error
+		return
+	}
+
+	// Successful refresh
+	negotiate.Render(c, http.StatusOK, gin.H{
+		"data":    NewTokenResponse(tokens),
+		"message": "Token refreshed successfully",
+	})
+}
+
+// Logout revokes the presented access token and the refresh tokens of its
+// session.
+func (h *Handler) Logout(c *gin.Context) {
+	// 1. Get the verified claims (set by AuthMiddleware)
+	claims, ok := ContextClaims(c)
+	if !ok {
+		c.Error(problem.New(http.StatusUnauthorized, "Invalid or missing token"))
+		return
+	}
+
+	// 2. Call service to revoke the token and its session
+	if err := h.Service.Logout(claims); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeSessions signs a user out everywhere by revoking every token issued
+// to them so far.
+func (h *Handler) RevokeSessions(c *gin.Context) {
+	// 1. Convert string URL param to uint
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid user ID format"))
+		return
+	}
+
+	// 2. Call service to revoke the user's sessions
+	if err := h.Service.RevokeSessions(uint(id)); err != nil {
+		if errors.Is(err, dberr.ErrNotFound) {
+			c.Error(problem.New(http.StatusNotFound, "user not found"))
+		} else {
+			c.Error(err)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// JWKS publishes the public keys access tokens can be verified with, so other
+// services can verify them without sharing a secret.
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Content-Type", "application/jwk-set+json")
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.Service.JWKS())
+}
+
+// CreateInvitation issues a single-use invitation code granting a role at
+// signup.
+func (h *Handler) CreateInvitation(c *gin.Context) {
+	var req InvitationRequest
+	if err := negotiate.Bind(c, &req); err != nil {
+		c.Error(problem.New(negotiate.BindStatus(err), err.Error()))
+		return
+	}
+
+	// 1. Get the inviting admin (set by AuthMiddleware)
+	claims, ok := ContextClaims(c)
+	if !ok {
+		c.Error(problem.New(http.StatusUnauthorized, "Invalid or missing token"))
+		return
+	}
+
+	// 2. Call service to create the invitation
+	inv, code, err := h.Service.CreateInvitation(req.Role, claims.ID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRole) {
+			c.Error(problem.New(http.StatusUnprocessableEntity, err.Error()))
+		} else {
+			c.Error(err)
+		}
+		return
+	}
+
+	negotiate.Render(c, http.StatusCreated, gin.H{
 		"data": gin.H{
-			"token": token,
+			"invitation": NewInvitationResponse(inv, code),
 		},
-		"message": "Login successful",
+		"message": "Invitation created successfully",
 	})
 }
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"gin-quickstart/internal/config"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKey is returned when a token's kid header names no configured
+// verification key.
+var ErrUnknownKey = errors.New("token signed with an unknown key")
+
+// KeySet holds the keys access tokens are signed and verified with.
+//
+// With an asymmetric signing key configured (RS256, ES256/384/512 or EdDSA,
+// chosen by the key type), tokens are signed with it and carry its kid in
+// the header. They verify against any configured key, so a new signing key
+// can be rolled out while tokens of the previous one are still accepted.
+// The public keys are published as a JWK Set. Without a signing key, tokens
+// are signed and verified with the shared HMAC secret (HS256).
+type KeySet struct {
+	signing *key
+	secret  []byte
+	// verifying are the keys by kid; order keeps the configured order.
+	verifying map[string]*key
+	order     []string
+}
+
+// key is an asymmetric key; private is nil for verification-only keys.
+type key struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// NewKeySet loads the keys configured in cfg: the PEM private key at
+// JWTSigningKey, if set, and the PEM public or private keys at
+// JWTVerificationKeys.
+func NewKeySet(cfg config.AppConfig) (*KeySet, error) {
+	ks := &KeySet{secret: []byte(cfg.JWTSecret), verifying: make(map[string]*key)}
+	if cfg.JWTSigningKey == "" {
+		if len(cfg.JWTVerificationKeys) > 0 {
+			return nil, errors.New("JWT_VERIFICATION_KEYS requires JWT_SIGNING_KEY")
+		}
+		if cfg.JWTSecret == "" {
+			return nil, errors.New("missing JWT_SECRET or JWT_SIGNING_KEY")
+		}
+		return ks, nil
+	}
+
+	signing, err := loadKey(cfg.JWTSigningKey)
+	if err != nil {
+		return nil, err
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("%s: signing key must be a private key", cfg.JWTSigningKey)
+	}
+	ks.signing = signing
+	ks.add(signing)
+
+	for _, path := range cfg.JWTVerificationKeys {
+		k, err := loadKey(path)
+		if err != nil {
+			return nil, err
+		}
+		ks.add(k)
+	}
+	return ks, nil
+}
+
+// add registers k for verification, once per kid.
+func (ks *KeySet) add(k *key) {
+	if _, ok := ks.verifying[k.kid]; ok {
+		return
+	}
+	ks.verifying[k.kid] = k
+	ks.order = append(ks.order, k.kid)
+}
+
+// Sign signs claims with the signing key, or the HMAC secret.
+func (ks *KeySet) Sign(claims Claims) (string, error) {
+	if ks.signing == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+	token := jwt.NewWithClaims(ks.signing.method, claims)
+	token.Header["kid"] = ks.signing.kid
+	return token.SignedString(ks.signing.private)
+}
+
+// keyFor is the jwt.Keyfunc choosing the key a token must verify against.
+// HMAC tokens are only accepted while no signing key is configured, so a
+// public key can never be used as an HMAC secret.
+func (ks *KeySet) keyFor(token *jwt.Token) (any, error) {
+	if ks.signing == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrTokenMalformed
+		}
+		return ks.secret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	k, ok := ks.verifying[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return k.public, nil
+}
+
+// JWKS returns the public verification keys as a JWK Set. It is empty when
+// tokens are signed with the HMAC secret, which must never be published.
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(ks.order))}
+	for _, kid := range ks.order {
+		k := ks.verifying[kid]
+		jwk := publicJWK(k.public)
+		jwk.Kid = k.kid
+		jwk.Alg = k.method.Alg()
+		jwk.Use = "sig"
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// JWKSet is an RFC 7517 JSON Web Key Set.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is the RFC 7517 representation of a public key. Its members are in
+// alphabetical order so the required ones marshal as the RFC 7638
+// thumbprint input.
+type JWK struct {
+	Alg string `json:"alg,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Kty string `json:"kty"`
+	N   string `json:"n,omitempty"`
+	Use string `json:"use,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// loadKey reads a PEM private or public key and derives its algorithm and
+// kid, the RFC 7638 thumbprint of the public key.
+func loadKey(path string) (*key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	var k key
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported private key type %T", path, parsed)
+		}
+		k.private = signer
+	case "RSA PRIVATE KEY":
+		if k.private, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case "EC PRIVATE KEY":
+		if k.private, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case "PUBLIC KEY":
+		if k.public, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case "RSA PUBLIC KEY":
+		if k.public, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if k.private != nil {
+		k.public = k.private.Public()
+	}
+
+	if k.method, err = methodFor(k.public); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	thumbprint, err := json.Marshal(publicJWK(k.public))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(thumbprint)
+	k.kid = base64.RawURLEncoding.EncodeToString(sum[:])
+	return &k, nil
+}
+
+// methodFor picks the signing algorithm matching a public key.
+func methodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, errors.New("unsupported elliptic curve")
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %T", public)
+}
+
+// publicJWK describes a public key by its required JWK members only.
+func publicJWK(public crypto.PublicKey) JWK {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{Kty: "RSA", N: b64(pub.N.Bytes()), E: b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		// The uncompressed point is 0x04 || X || Y, both padded to the curve size
+		point, err := pub.Bytes()
+		if err != nil {
+			return JWK{Kty: "EC"}
+		}
+		size := (len(point) - 1) / 2
+		return JWK{Kty: "EC", Crv: pub.Curve.Params().Name, X: b64(point[1 : 1+size]), Y: b64(point[1+size:])}
+	case ed25519.PublicKey:
+		return JWK{Kty: "OKP", Crv: "Ed25519", X: b64(pub)}
+	}
+	return JWK{}
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"gin-quickstart/internal/config"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey stores key in a PEM file under t's temporary directory. Private
+// keys are written as PKCS #8, public keys as PKIX.
+func writeKey(t *testing.T, name string, key any) string {
+	t.Helper()
+	var block *pem.Block
+	if signer, ok := key.(crypto.Signer); ok {
+		der, err := x509.MarshalPKCS8PrivateKey(signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	} else {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func thumbprint(canonical string) string {
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// TestPublicJWKThumbprintRFC7638 checks the thumbprint of the example key in
+// RFC 7638 §3.1.
+func TestPublicJWKThumbprintRFC7638(t *testing.T) {
+	n, err := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwk, err := json.Marshal(publicJWK(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs"
+	if got := thumbprint(string(jwk)); got != want {
+		t.Errorf("thumbprint = %s, want %s", got, want)
+	}
+}
+
+func TestLoadKeyKid(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	ecPoint, err := ecKey.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaCanonical := `{"e":"AQAB","kty":"RSA","n":"` + b64(rsaKey.N.Bytes()) + `"}`
+	ecCanonical := `{"crv":"P-384","kty":"EC","x":"` + b64(ecPoint[1:49]) + `","y":"` + b64(ecPoint[49:]) + `"}`
+	edCanonical := `{"crv":"Ed25519","kty":"OKP","x":"` + b64(edPublic) + `"}`
+
+	tests := []struct {
+		name      string
+		key       any
+		canonical string
+		alg       string
+	}{
+		{"RSA private", rsaKey, rsaCanonical, "RS256"},
+		{"RSA public", &rsaKey.PublicKey, rsaCanonical, "RS256"},
+		{"EC private", ecKey, ecCanonical, "ES384"},
+		{"EC public", &ecKey.PublicKey, ecCanonical, "ES384"},
+		{"Ed25519 private", edKey, edCanonical, "EdDSA"},
+		{"Ed25519 public", edPublic, edCanonical, "EdDSA"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := loadKey(writeKey(t, "key.pem", tt.key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := thumbprint(tt.canonical); k.kid != want {
+				t.Errorf("kid = %s, want %s", k.kid, want)
+			}
+			if k.method.Alg() != tt.alg {
+				t.Errorf("alg = %s, want %s", k.method.Alg(), tt.alg)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldPath, newPath := writeKey(t, "old.pem", oldKey), writeKey(t, "new.pem", newKey)
+
+	oldSet, err := NewKeySet(config.AppConfig{JWTSigningKey: oldPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewKeySet(config.AppConfig{JWTSigningKey: newPath, JWTVerificationKeys: []string{oldPath}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. The JWKS lists the signing key first, then the previous key, public parts only
+	jwks := rotated.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want 2", len(jwks.Keys))
+	}
+	if jwks.Keys[0].Kid != rotated.signing.kid || jwks.Keys[0].Alg != "ES256" || jwks.Keys[0].Use != "sig" {
+		t.Errorf("first JWK = %+v", jwks.Keys[0])
+	}
+	if jwks.Keys[1].Kid != oldSet.signing.kid || jwks.Keys[1].Alg != "EdDSA" {
+		t.Errorf("second JWK = %+v", jwks.Keys[1])
+	}
+
+	// 2. Tokens of both keys verify; their kid names the key
+	opts := TokenOptions{Issuer: "test", Audience: []string{"test"}, TTL: time.Minute}
+	user := User{Role: RoleUser}
+	user.ID = 1
+	for _, ks := range []*KeySet{oldSet, rotated} {
+		token, err := GenerateToken(user, "", ks, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Header["kid"] != ks.signing.kid {
+			t.Errorf("kid header = %v, want %s", parsed.Header["kid"], ks.signing.kid)
+		}
+		if _, err := VerifyToken(token, rotated, opts); err != nil {
+			t.Errorf("token signed with %s: %v", ks.signing.method.Alg(), err)
+		}
+	}
+
+	// 3. Unknown keys and HMAC tokens are rejected
+	_, strangerKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger, err := NewKeySet(config.AppConfig{JWTSigningKey: writeKey(t, "stranger.pem", strangerKey)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	strangerToken, err := GenerateToken(user, "", stranger, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyToken(strangerToken, rotated, opts); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token of an unknown key: %v, want ErrUnknownKey", err)
+	}
+	hmacSet, err := NewKeySet(config.AppConfig{JWTSecret: "a-secret-of-at-least-32-characters"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacToken, err := GenerateToken(user, "", hmacSet, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyToken(hmacToken, rotated, opts); err == nil {
+		t.Error("HS256 token accepted while a signing key is configured")
+	}
+	if keys := hmacSet.JWKS().Keys; len(keys) != 0 {
+		t.Errorf("HMAC key set publishes %d keys", len(keys))
+	}
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privatePath := writeKey(t, "private.pem", private)
+	publicPath := writeKey(t, "public.pem", private.Public())
+
+	tests := []struct {
+		name string
+		cfg  config.AppConfig
+	}{
+		{"no secret and no signing key", config.AppConfig{}},
+		{"verification keys without signing key", config.AppConfig{JWTSecret: "secret", JWTVerificationKeys: []string{publicPath}}},
+		{"public signing key", config.AppConfig{JWTSigningKey: publicPath}},
+		{"missing signing key file", config.AppConfig{JWTSigningKey: filepath.Join(t.TempDir(), "missing.pem")}},
+		{"missing verification key file", config.AppConfig{JWTSigningKey: privatePath, JWTVerificationKeys: []string{filepath.Join(t.TempDir(), "missing.pem")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeySet(tt.cfg); err == nil {
+				t.Error("NewKeySet succeeded")
+			}
+		})
+	}
+}
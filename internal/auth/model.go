@@ -1,21 +1,65 @@
 package auth
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Roles a user can hold. Self-registered users get RoleUser; other roles are
+// granted by invitation.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// validRoles are the roles an invitation can grant.
+var validRoles = map[string]bool{RoleUser: true, RoleAdmin: true}
 
 type User struct {
 	Username     string `json:"username" gorm:"unique;not null"`
 	PasswordHash string `json:"-" gorm:"not null"`
-	Role         string `json:"role" binding:"required"`
+	Role         string `json:"role"`
+	// SessionsRevokedAt invalidates every token issued to the user before it.
+	SessionsRevokedAt *time.Time `json:"-"`
 	gorm.Model
 }
 
-type RegisterRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	Role     string `json:"role" binding:"required"`
+// RefreshToken is an issued refresh token; only its SHA-256 hash is stored.
+// Every token rotated from the same login shares its FamilyID, so replaying
+// a used token can revoke the whole chain.
+type RefreshToken struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	// UsedAt is set when the token is exchanged for its successor.
+	UsedAt *time.Time
+	// RevokedAt is set when its family is revoked.
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// RevokedToken denylists an access token by its jti until the token expires.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
 }
 
-type LoginRequest struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+// Invitation lets one person sign up with Role. Only the SHA-256 hash of the
+// code is stored; the code itself is shown once, when the invitation is
+// created.
+type Invitation struct {
+	ID        uint      `gorm:"primarykey"`
+	CodeHash  string    `gorm:"not null;uniqueIndex"`
+	Role      string    `gorm:"not null"`
+	CreatedBy uint      `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	// UsedAt and UsedBy are set when someone signs up with the code.
+	UsedAt    *time.Time
+	UsedBy    *uint
+	CreatedAt time.Time
 }
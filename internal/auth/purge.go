@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartRevocationPurger runs Revocations.PurgeExpired every interval until
+// ctx is cancelled. It returns immediately; the purge loop runs in its own
+// goroutine.
+func StartRevocationPurger(ctx context.Context, r Revocations, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := r.PurgeExpired()
+			if err != nil {
+				log.Printf("⚠️ revoked token purge failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("🗑️ purged %d expired revoked token(s)", purged)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -1,10 +1,39 @@
 package auth
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
 
 type AuthRepository interface {
 	Create(user User) (User, error)
 	FindByUsername(username string) (User, error)
+	FindById(id uint) (User, error)
+	CreateRefreshToken(token RefreshToken) error
+	FindRefreshToken(hash string) (RefreshToken, error)
+	// MarkRefreshTokenUsed sets used_at unless the token was already used or
+	// revoked, and reports whether it did.
+	MarkRefreshTokenUsed(id uint, at time.Time) (bool, error)
+	RevokeRefreshFamily(familyID string, at time.Time) error
+	RevokeToken(token RevokedToken) error
+	IsTokenRevoked(jti string) (bool, error)
+	// RevokeUserSessions sets the user's sessions_revoked_at and revokes all
+	// of their refresh tokens.
+	RevokeUserSessions(userID uint, at time.Time) error
+	// PurgeRevokedTokens deletes denylist entries of tokens expired before
+	// cutoff and returns how many were deleted.
+	PurgeRevokedTokens(cutoff time.Time) (int64, error)
+	CountByRole(role string) (int64, error)
+	CreateInvitation(inv Invitation) (Invitation, error)
+	FindInvitation(codeHash string) (Invitation, error)
+	// UseInvitation marks the invitation used by userID unless it was already
+	// used or has expired, and reports whether it did.
+	UseInvitation(id, userID uint, at time.Time) (bool, error)
+	Transaction(fn func(repo AuthRepository) error) error
 }
 
 type authRepository struct {
@@ -17,6 +46,9 @@ func NewRepository(db *gorm.DB) AuthRepository {
 
 func (r *authRepository) Create(user User) (User, error) {
 	if err := r.DB.Create(&user).Error; err != nil {
+		if errors.Is(err, dberr.ErrDuplicate) {
+			return User{}, ErrUsernameTaken
+		}
 		return User{}, err
 	}
 	return user, nil
@@ -29,3 +61,108 @@ func (r *authRepository) FindByUsername(username string) (User, error) {
 	}
 	return user, nil
 }
+
+func (r *authRepository) FindById(id uint) (User, error) {
+	var user User
+	if err := r.DB.First(&user, "id = ?", id).Error; err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+func (r *authRepository) CreateRefreshToken(token RefreshToken) error {
+	return r.DB.Create(&token).Error
+}
+
+func (r *authRepository) FindRefreshToken(hash string) (RefreshToken, error) {
+	var token RefreshToken
+	if err := r.DB.First(&token, "token_hash = ?", hash).Error; err != nil {
+		return RefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (r *authRepository) MarkRefreshTokenUsed(id uint, at time.Time) (bool, error) {
+	result := r.DB.Model(&RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *authRepository) RevokeRefreshFamily(familyID string, at time.Time) error {
+	return r.DB.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+}
+
+func (r *authRepository) RevokeToken(token RevokedToken) error {
+	return r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&token).Error
+}
+
+func (r *authRepository) IsTokenRevoked(jti string) (bool, error) {
+	var count int64
+	if err := r.DB.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *authRepository) RevokeUserSessions(userID uint, at time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).Where("id = ?", userID).Update("sessions_revoked_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return dberr.ErrNotFound
+		}
+		return tx.Model(&RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", at).Error
+	})
+}
+
+func (r *authRepository) PurgeRevokedTokens(cutoff time.Time) (int64, error) {
+	result := r.DB.Where("expires_at < ?", cutoff).Delete(&RevokedToken{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *authRepository) CountByRole(role string) (int64, error) {
+	var count int64
+	err := r.DB.Model(&User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
+func (r *authRepository) CreateInvitation(inv Invitation) (Invitation, error) {
+	if err := r.DB.Create(&inv).Error; err != nil {
+		return Invitation{}, err
+	}
+	return inv, nil
+}
+
+func (r *authRepository) FindInvitation(codeHash string) (Invitation, error) {
+	var inv Invitation
+	if err := r.DB.First(&inv, "code_hash = ?", codeHash).Error; err != nil {
+		return Invitation{}, err
+	}
+	return inv, nil
+}
+
+func (r *authRepository) UseInvitation(id, userID uint, at time.Time) (bool, error) {
+	result := r.DB.Model(&Invitation{}).
+		Where("id = ? AND used_at IS NULL AND expires_at > ?", id, at).
+		Updates(map[string]any{"used_at": at, "used_by": userID})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *authRepository) Transaction(fn func(repo AuthRepository) error) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(&authRepository{DB: tx})
+	})
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+	"sync"
+	"time"
+)
+
+// Revocations tracks access tokens that are no longer accepted although they
+// have not expired: single tokens denylisted by jti on logout, and every
+// token of a user issued before their sessions were revoked or their account
+// was deleted.
+type Revocations interface {
+	IsRevoked(claims *Claims) (bool, error)
+	RevokeToken(claims *Claims) error
+	RevokeUser(userID uint) error
+	// PurgeExpired forgets revoked tokens that have expired anyway and
+	// returns how many were removed.
+	PurgeExpired() (int64, error)
+}
+
+// revocationStore keeps revocations in the database and caches lookups in
+// memory for ttl, so most requests do not query it. Revocations made by
+// another instance are therefore seen after at most ttl.
+type revocationStore struct {
+	repo AuthRepository
+	ttl  time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]cached[bool]
+	users  map[uint]cached[userSessions]
+}
+
+// cached is a cache entry valid until expires.
+type cached[T any] struct {
+	value   T
+	expires time.Time
+}
+
+// userSessions is what revocation needs to know about a token's user.
+type userSessions struct {
+	deleted   bool
+	revokedAt *time.Time
+}
+
+// NewRevocationStore returns a database-backed Revocations caching lookups
+// for ttl.
+func NewRevocationStore(repo AuthRepository, ttl time.Duration) Revocations {
+	return &revocationStore{
+		repo:   repo,
+		ttl:    ttl,
+		tokens: make(map[string]cached[bool]),
+		users:  make(map[uint]cached[userSessions]),
+	}
+}
+
+func (s *revocationStore) IsRevoked(claims *Claims) (bool, error) {
+	now := time.Now()
+
+	// 1. The token itself was revoked by logging out
+	if jti := claims.RegisteredClaims.ID; jti != "" {
+		revoked, err := s.tokenRevoked(jti, now)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	// 2. The user is gone, or all of their sessions were revoked after the token was issued
+	user, err := s.userSessions(claims.ID, now)
+	if err != nil {
+		return false, err
+	}
+	if user.deleted {
+		return true, nil
+	}
+	if user.revokedAt == nil {
+		return false, nil
+	}
+	return claims.IssuedAt == nil || claims.IssuedAt.Time.Before(*user.revokedAt), nil
+}
+
+func (s *revocationStore) RevokeToken(claims *Claims) error {
+	jti := claims.RegisteredClaims.ID
+	if jti == "" {
+		return errors.New("token has no jti")
+	}
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no expiry")
+	}
+	expiresAt := claims.ExpiresAt.Time
+	if err := s.repo.RevokeToken(RevokedToken{JTI: jti, UserID: claims.ID, ExpiresAt: expiresAt}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = cached[bool]{value: true, expires: expiresAt}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *revocationStore) RevokeUser(userID uint) error {
+	now := time.Now()
+	if err := s.repo.RevokeUserSessions(userID, now); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.users[userID] = cached[userSessions]{value: userSessions{revokedAt: &now}, expires: now.Add(s.ttl)}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *revocationStore) PurgeExpired() (int64, error) {
+	now := time.Now()
+	s.mu.Lock()
+	for jti, entry := range s.tokens {
+		if !now.Before(entry.expires) {
+			delete(s.tokens, jti)
+		}
+	}
+	for id, entry := range s.users {
+		if !now.Before(entry.expires) {
+			delete(s.users, id)
+		}
+	}
+	s.mu.Unlock()
+	return s.repo.PurgeRevokedTokens(now)
+}
+
+// tokenRevoked looks the jti up in the denylist, through the cache.
+func (s *revocationStore) tokenRevoked(jti string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	entry, ok := s.tokens[jti]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	revoked, err := s.repo.IsTokenRevoked(jti)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.tokens[jti] = cached[bool]{value: revoked, expires: now.Add(s.ttl)}
+	s.mu.Unlock()
+	return revoked, nil
+}
+
+// userSessions loads the user's revocation state, through the cache.
+func (s *revocationStore) userSessions(userID uint, now time.Time) (userSessions, error) {
+	s.mu.Lock()
+	entry, ok := s.users[userID]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	var state userSessions
+	user, err := s.repo.FindById(userID)
+	switch {
+	case errors.Is(err, dberr.ErrNotFound):
+		state.deleted = true
+	case err != nil:
+		return userSessions{}, err
+	default:
+		state.revokedAt = user.SessionsRevokedAt
+	}
+	s.mu.Lock()
+	s.users[userID] = cached[userSessions]{value: state, expires: now.Add(s.ttl)}
+	s.mu.Unlock()
+	return state, nil
+}
@@ -2,25 +2,66 @@ package auth
 
 import (
 	"errors"
+	"fmt"
 	"gin-quickstart/internal/config"
+	"gin-quickstart/internal/dberr"
+	"time"
 )
 
-var ErrInvalidCredentials = errors.New("invalid credentials")
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUsernameTaken is returned when signing up with a username that is
+	// already registered.
+	ErrUsernameTaken = errors.New("username already taken")
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked
+	// refresh tokens.
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	// ErrRefreshTokenReused is returned when a refresh token is presented a
+	// second time. The whole token family is revoked, since either the
+	// client or an attacker holds a stolen copy.
+	ErrRefreshTokenReused = errors.New("refresh token was already used; the session has been revoked")
+	// ErrInvalidInvitation is returned when signing up with an unknown,
+	// expired or already used invitation code.
+	ErrInvalidInvitation = errors.New("invitation code is invalid, expired or already used")
+	// ErrInvalidRole is returned when inviting someone to an unknown role.
+	ErrInvalidRole = errors.New("role must be user or admin")
+)
+
+// TokenPair is what a login or a refresh issues: a short-lived access token
+// and the refresh token that renews it.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	// ExpiresIn is the lifetime of the access token.
+	ExpiresIn time.Duration
+}
 
 type AuthService interface {
 	SignUp(req RegisterRequest) (User, error)
-	Login(req LoginRequest) (string, error)
+	CreateInvitation(role string, createdBy uint) (Invitation, string, error)
+	BootstrapAdmin(username, password string) (bool, error)
+	Login(req LoginRequest) (TokenPair, error)
+	Refresh(refreshToken string) (TokenPair, error)
+	Logout(claims *Claims) error
+	RevokeSessions(userID uint) error
+	JWKS() JWKSet
 }
 
 type authService struct {
-	Repo AuthRepository
-	Cfg  config.Config
+	Repo        AuthRepository
+	Revocations Revocations
+	Keys        *KeySet
+	Tokens      TokenOptions
+	Cfg         config.Config
 }
 
-func NewService(repo AuthRepository, cfg config.Config) AuthService {
+func NewService(repo AuthRepository, revocations Revocations, keys *KeySet, cfg config.Config) AuthService {
 	return &authService{
-		Repo: repo,
-		Cfg:  cfg,
+		Repo:        repo,
+		Revocations: revocations,
+		Keys:        keys,
+		Tokens:      NewTokenOptions(cfg.App),
+		Cfg:         cfg,
 	}
 }
 
@@ -32,27 +73,202 @@ func (s *authService) SignUp(req RegisterRequest) (User, error) {
 	user := User{
 		Username:     req.Username,
 		PasswordHash: hashedPassword,
-		Role:         req.Role,
+		Role:         RoleUser,
+	}
+	if req.InviteCode == "" {
+		return s.Repo.Create(user)
 	}
-	createdUser, err := s.Repo.Create(user)
+
+	// Invited users get the invitation's role; the code is consumed together
+	// with creating the account, so it works exactly once
+	var createdUser User
+	err = s.Repo.Transaction(func(repo AuthRepository) error {
+		inv, err := repo.FindInvitation(HashToken(req.InviteCode))
+		if errors.Is(err, dberr.ErrNotFound) {
+			return ErrInvalidInvitation
+		}
+		if err != nil {
+			return err
+		}
+		user.Role = inv.Role
+		if createdUser, err = repo.Create(user); err != nil {
+			return err
+		}
+		used, err := repo.UseInvitation(inv.ID, createdUser.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidInvitation
+		}
+		return nil
+	})
 	if err != nil {
 		return User{}, err
 	}
 	return createdUser, nil
 }
 
-func (s *authService) Login(req LoginRequest) (string, error) {
+// CreateInvitation issues a single-use invitation to role, valid for the
+// configured invitation lifetime. The code is only returned here.
+func (s *authService) CreateInvitation(role string, createdBy uint) (Invitation, string, error) {
+	if !validRoles[role] {
+		return Invitation{}, "", ErrInvalidRole
+	}
+	code, hash, err := GenerateInviteCode()
+	if err != nil {
+		return Invitation{}, "", err
+	}
+	inv, err := s.Repo.CreateInvitation(Invitation{
+		CodeHash:  hash,
+		Role:      role,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(s.Cfg.App.InvitationTTL),
+	})
+	if err != nil {
+		return Invitation{}, "", err
+	}
+	return inv, code, nil
+}
+
+// BootstrapAdmin creates the first admin account when there is none yet, so
+// a fresh installation can issue invitations. It does nothing if username is
+// empty or an admin exists, and reports whether it created the account.
+func (s *authService) BootstrapAdmin(username, password string) (bool, error) {
+	if username == "" || password == "" {
+		return false, nil
+	}
+	admins, err := s.Repo.CountByRole(RoleAdmin)
+	if err != nil || admins > 0 {
+		return false, err
+	}
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return false, err
+	}
+	_, err = s.Repo.Create(User{Username: username, PasswordHash: hashedPassword, Role: RoleAdmin})
+	if errors.Is(err, ErrUsernameTaken) {
+		// Another instance may have bootstrapped the same account concurrently
+		existing, findErr := s.Repo.FindByUsername(username)
+		if findErr == nil && existing.Role == RoleAdmin {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot create admin %q: %w", username, err)
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *authService) Login(req LoginRequest) (TokenPair, error) {
 	username := req.Username
 	user, err := s.Repo.FindByUsername(username)
 	if err != nil {
-		return "", err
+		return TokenPair{}, err
 	}
 	if !CheckPasswordHash(req.Password, user.PasswordHash) {
-		return "", ErrInvalidCredentials
+		return TokenPair{}, ErrInvalidCredentials
+	}
+	// Every login starts a new refresh token family
+	familyID, err := randomString(16)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return s.issueTokens(user, familyID)
+}
+
+// Refresh exchanges a refresh token for a new pair. Each refresh token is
+// single-use: the successor joins the same family, and presenting a token
+// that was already exchanged revokes the family.
+func (s *authService) Refresh(refreshToken string) (TokenPair, error) {
+	now := time.Now()
+
+	// 1. Look the token up by its hash
+	stored, err := s.Repo.FindRefreshToken(HashToken(refreshToken))
+	if errors.Is(err, dberr.ErrNotFound) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	// 2. A replayed token means it leaked; end every session of the family
+	if stored.UsedAt != nil {
+		return TokenPair{}, s.revokeFamily(stored.FamilyID, now)
+	}
+	if stored.RevokedAt != nil || !now.Before(stored.ExpiresAt) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	// 3. Consume the token; losing a race against a concurrent use is a replay too
+	used, err := s.Repo.MarkRefreshTokenUsed(stored.ID, now)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if !used {
+		return TokenPair{}, s.revokeFamily(stored.FamilyID, now)
+	}
+
+	// 4. Issue the successor pair for the user's current role
+	user, err := s.Repo.FindById(stored.UserID)
+	if errors.Is(err, dberr.ErrNotFound) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return s.issueTokens(user, stored.FamilyID)
+}
+
+// Logout revokes the access token described by claims and the refresh
+// tokens of its session.
+func (s *authService) Logout(claims *Claims) error {
+	if err := s.Revocations.RevokeToken(claims); err != nil {
+		return err
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+	return s.Repo.RevokeRefreshFamily(claims.SessionID, time.Now())
+}
+
+// RevokeSessions revokes every access and refresh token issued to the user.
+func (s *authService) RevokeSessions(userID uint) error {
+	return s.Revocations.RevokeUser(userID)
+}
+
+// JWKS returns the public keys tokens can be verified with.
+func (s *authService) JWKS() JWKSet {
+	return s.Keys.JWKS()
+}
+
+// revokeFamily revokes a refresh token family after a replay and returns
+// ErrRefreshTokenReused.
+func (s *authService) revokeFamily(familyID string, now time.Time) error {
+	if err := s.Repo.RevokeRefreshFamily(familyID, now); err != nil {
+		return err
 	}
-	token, err := GenerateToken(user, []byte(s.Cfg.App.JWTSecret))
+	return ErrRefreshTokenReused
+}
+
+// issueTokens signs an access token and stores a new refresh token in familyID.
+func (s *authService) issueTokens(user User, familyID string) (TokenPair, error) {
+	accessToken, err := GenerateToken(user, familyID, s.Keys, s.Tokens)
 	if err != nil {
-		return "", err
+		return TokenPair{}, err
+	}
+	refreshToken, hash, err := GenerateRefreshToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := s.Repo.CreateRefreshToken(RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(s.Cfg.App.RefreshTokenTTL),
+	}); err != nil {
+		return TokenPair{}, err
 	}
-	return token, nil
+	return TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: s.Tokens.TTL}, nil
 }
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"errors"
+	"gin-quickstart/internal/config"
+	"gin-quickstart/internal/dberr"
+	"slices"
+	"testing"
+	"time"
+)
+
+// refreshRepo keeps users and refresh tokens in memory. Methods the tests
+// do not reach are left to the nil embedded interface.
+type refreshRepo struct {
+	AuthRepository
+	users  map[uint]User
+	tokens []RefreshToken
+	// loseRace makes MarkRefreshTokenUsed report that a concurrent request
+	// consumed the token first.
+	loseRace bool
+	revoked  []string
+}
+
+func (r *refreshRepo) FindById(id uint) (User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return User{}, dberr.ErrNotFound
+	}
+	return user, nil
+}
+
+func (r *refreshRepo) CreateRefreshToken(token RefreshToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *refreshRepo) FindRefreshToken(hash string) (RefreshToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return RefreshToken{}, dberr.ErrNotFound
+}
+
+func (r *refreshRepo) MarkRefreshTokenUsed(id uint, at time.Time) (bool, error) {
+	token := &r.tokens[id-1]
+	if r.loseRace || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	token.UsedAt = &at
+	return true, nil
+}
+
+func (r *refreshRepo) RevokeRefreshFamily(familyID string, at time.Time) error {
+	r.revoked = append(r.revoked, familyID)
+	for i := range r.tokens {
+		if r.tokens[i].FamilyID == familyID && r.tokens[i].RevokedAt == nil {
+			r.tokens[i].RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+func newRefreshService(t *testing.T) (*authService, *refreshRepo) {
+	t.Helper()
+	keys, err := NewKeySet(config.AppConfig{JWTSecret: "a-secret-of-at-least-32-characters"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg config.Config
+	cfg.App.AccessTokenTTL = time.Minute
+	cfg.App.RefreshTokenTTL = time.Hour
+	user := User{Username: "alice", Role: RoleUser}
+	user.ID = 1
+	repo := &refreshRepo{users: map[uint]User{1: user}}
+	return NewService(repo, nil, keys, cfg).(*authService), repo
+}
+
+func TestRefreshRotation(t *testing.T) {
+	s, repo := newRefreshService(t)
+	first, err := s.issueTokens(repo.users[1], "family")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1. A fresh token is exchanged for a successor in the same family
+	second, err := s.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("first refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+	claims, err := VerifyToken(second.AccessToken, s.Keys, s.Tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SessionID != "family" {
+		t.Errorf("sid = %q, want family", claims.SessionID)
+	}
+
+	// 2. Replaying the consumed token revokes the whole family
+	if _, err := s.Refresh(first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replay: %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := s.Refresh(second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("successor after replay: %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name    string
+		prepare func(repo *refreshRepo, token *RefreshToken)
+		wantErr error
+		revoked bool
+	}{
+		{"unknown token", func(repo *refreshRepo, token *RefreshToken) { token.TokenHash = "other" }, ErrInvalidRefreshToken, false},
+		{"expired", func(repo *refreshRepo, token *RefreshToken) { token.ExpiresAt = past }, ErrInvalidRefreshToken, false},
+		{"revoked", func(repo *refreshRepo, token *RefreshToken) { token.RevokedAt = &past }, ErrInvalidRefreshToken, false},
+		{"already used", func(repo *refreshRepo, token *RefreshToken) { token.UsedAt = &past }, ErrRefreshTokenReused, true},
+		{"used concurrently", func(repo *refreshRepo, token *RefreshToken) { repo.loseRace = true }, ErrRefreshTokenReused, true},
+		{"user deleted", func(repo *refreshRepo, token *RefreshToken) { delete(repo.users, 1) }, ErrInvalidRefreshToken, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repo := newRefreshService(t)
+			pair, err := s.issueTokens(repo.users[1], "family")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.issueTokens(repo.users[1], "other-family"); err != nil {
+				t.Fatal(err)
+			}
+			tt.prepare(repo, &repo.tokens[0])
+
+			if _, err := s.Refresh(pair.RefreshToken); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Refresh error = %v, want %v", err, tt.wantErr)
+			}
+			var wantRevoked []string
+			if tt.revoked {
+				wantRevoked = []string{"family"}
+			}
+			if !slices.Equal(repo.revoked, wantRevoked) {
+				t.Errorf("revoked families = %q, want %q", repo.revoked, wantRevoked)
+			}
+			if len(repo.tokens) != 2 {
+				t.Errorf("%d refresh tokens stored, want no successor", len(repo.tokens))
+			}
+		})
+	}
+}
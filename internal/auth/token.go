@@ -1,51 +1,96 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"gin-quickstart/internal/config"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// TokenOptions are the registered claims access tokens are issued with and
+// checked against.
+type TokenOptions struct {
+	Issuer   string
+	Audience []string
+	// TTL is how long an access token is valid. Clients renew it with their
+	// refresh token rather than logging in again.
+	TTL time.Duration
+	// Leeway tolerates clock skew between servers when checking exp, nbf
+	// and iat.
+	Leeway time.Duration
+}
+
+// NewTokenOptions reads the token options from the app configuration.
+func NewTokenOptions(cfg config.AppConfig) TokenOptions {
+	return TokenOptions{
+		Issuer:   cfg.JWTIssuer,
+		Audience: cfg.JWTAudience,
+		TTL:      cfg.AccessTokenTTL,
+		Leeway:   cfg.JWTLeeway,
+	}
+}
+
+// Claims are the claims of an access token. RegisteredClaims.ID is the
+// token's jti, by which it can be revoked; Subject repeats the user ID as a
+// string.
 type Claims struct {
 	ID   uint   `json:"id"`
 	Role string `json:"role"`
+	// SessionID is the refresh token family the token was issued in.
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// GenerateToken generates a JWT token for the given user.
-func GenerateToken(user User, secret []byte) (string, error) {
+// GenerateToken generates a JWT token for the given user in the session
+// (refresh token family) sessionID, signed with keys.
+func GenerateToken(user User, sessionID string, keys *KeySet, opts TokenOptions) (string, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
 	claims := Claims{
-		ID:   user.ID,
-		Role: user.Role,
+		ID:        user.ID,
+		Role:      user.Role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        jti,
+			Issuer:    opts.Issuer,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Audience:  opts.Audience,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(opts.TTL)),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	tokenString, err := token.SignedString(secret)
-	if err != nil {
-		return "", err
-	}
-	return tokenString, nil
+	return keys.Sign(claims)
 }
 
-// VerifyToken verifies the JWT token and returns the claims if valid.
-func VerifyToken(tokenString string, secret []byte) (*Claims, error) {
+// VerifyToken verifies the JWT token against keys and returns the claims if
+// valid. Besides the signature and expiry, the issuer and audience must match
+// opts, iat and nbf must not lie in the future, and sub must name the user.
+func VerifyToken(tokenString string, keys *KeySet, opts TokenOptions) (*Claims, error) {
 	claims := &Claims{}
 
-	// Parse the token
-	token, err := jwt.ParseWithClaims(
-		tokenString,
-		claims,
-		func(token *jwt.Token) (any, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrTokenMalformed
-			}
-			return secret, nil
-		},
-	)
+	// Parse the token, validating the registered claims with the configured leeway
+	parserOptions := []jwt.ParserOption{
+		jwt.WithLeeway(opts.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+	if opts.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(opts.Issuer))
+	}
+	if len(opts.Audience) > 0 {
+		parserOptions = append(parserOptions, jwt.WithAudience(opts.Audience...))
+	}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.keyFor, parserOptions...)
 
 	// Check for parsing errors and validity
 	if err != nil {
@@ -56,11 +101,51 @@ func VerifyToken(tokenString string, secret []byte) (*Claims, error) {
 	if !token.Valid {
 		return nil, jwt.ErrTokenInvalidClaims
 	}
+	if claims.Subject != strconv.FormatUint(uint64(claims.ID), 10) {
+		return nil, jwt.ErrTokenInvalidSubject
+	}
 
 	// Return the claims if token is valid
 	return claims, nil
 }
 
+// GenerateRefreshToken returns a new opaque refresh token and the hash under
+// which it is stored.
+func GenerateRefreshToken() (token, hash string, err error) {
+	token, err = randomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// GenerateInviteCode returns a new invitation code and the hash under which
+// it is stored.
+func GenerateInviteCode() (code, hash string, err error) {
+	code, err = randomString(24)
+	if err != nil {
+		return "", "", err
+	}
+	return code, HashToken(code), nil
+}
+
+// HashToken derives the stored hash of an opaque token such as a refresh
+// token or an invitation code. The tokens are random, so an unsalted fast
+// hash is enough.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes, base64url-encoded.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // HashPassword hashes the given password using bcrypt.
 func HashPassword(password string) (string, error) {
 	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
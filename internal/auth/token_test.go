@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"gin-quickstart/internal/config"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestVerifyToken(t *testing.T) {
+	keys, err := NewKeySet(config.AppConfig{JWTSecret: "a-secret-of-at-least-32-characters"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeys, err := NewKeySet(config.AppConfig{JWTSecret: "another-secret-of-32-characters!"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := TokenOptions{
+		Issuer:   "gin-quickstart",
+		Audience: []string{"gin-quickstart", "mobile"},
+		TTL:      15 * time.Minute,
+		Leeway:   30 * time.Second,
+	}
+	now := time.Now()
+	at := func(d time.Duration) *jwt.NumericDate { return jwt.NewNumericDate(now.Add(d)) }
+
+	// valid returns claims accepted by opts; each case breaks one of them.
+	valid := func() Claims {
+		return Claims{
+			ID:   7,
+			Role: RoleUser,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        "jti",
+				Issuer:    "gin-quickstart",
+				Subject:   "7",
+				Audience:  jwt.ClaimStrings{"gin-quickstart"},
+				IssuedAt:  at(0),
+				NotBefore: at(0),
+				ExpiresAt: at(time.Minute),
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *Claims)
+		keys    *KeySet
+		wantErr error
+	}{
+		{"valid", func(c *Claims) {}, keys, nil},
+		{"second audience", func(c *Claims) { c.Audience = jwt.ClaimStrings{"mobile"} }, keys, nil},
+		{"one of several audiences", func(c *Claims) { c.Audience = jwt.ClaimStrings{"web", "mobile"} }, keys, nil},
+		{"wrong issuer", func(c *Claims) { c.Issuer = "someone-else" }, keys, jwt.ErrTokenInvalidIssuer},
+		{"no issuer", func(c *Claims) { c.Issuer = "" }, keys, jwt.ErrTokenRequiredClaimMissing},
+		{"wrong audience", func(c *Claims) { c.Audience = jwt.ClaimStrings{"web"} }, keys, jwt.ErrTokenInvalidAudience},
+		{"no audience", func(c *Claims) { c.Audience = nil }, keys, jwt.ErrTokenRequiredClaimMissing},
+		{"subject of another user", func(c *Claims) { c.Subject = "8" }, keys, jwt.ErrTokenInvalidSubject},
+		{"no subject", func(c *Claims) { c.Subject = "" }, keys, jwt.ErrTokenInvalidSubject},
+		{"nbf within leeway", func(c *Claims) { c.NotBefore = at(20 * time.Second) }, keys, nil},
+		{"nbf beyond leeway", func(c *Claims) { c.NotBefore = at(time.Minute) }, keys, jwt.ErrTokenNotValidYet},
+		{"iat within leeway", func(c *Claims) { c.IssuedAt = at(20 * time.Second) }, keys, nil},
+		{"iat beyond leeway", func(c *Claims) { c.IssuedAt = at(time.Minute) }, keys, jwt.ErrTokenUsedBeforeIssued},
+		{"expired within leeway", func(c *Claims) { c.ExpiresAt = at(-20 * time.Second) }, keys, nil},
+		{"expired beyond leeway", func(c *Claims) { c.ExpiresAt = at(-time.Minute) }, keys, jwt.ErrTokenExpired},
+		{"no expiry", func(c *Claims) { c.ExpiresAt = nil }, keys, jwt.ErrTokenRequiredClaimMissing},
+		{"signed with another secret", func(c *Claims) {}, otherKeys, jwt.ErrTokenSignatureInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.modify(&claims)
+			token, err := tt.keys.Sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := VerifyToken(token, keys, opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("VerifyToken error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyToken: %v", err)
+			}
+			if got.ID != 7 || got.RegisteredClaims.ID != "jti" {
+				t.Errorf("claims = %+v", got)
+			}
+		})
+	}
+}
+
+func TestGenerateTokenClaims(t *testing.T) {
+	keys, err := NewKeySet(config.AppConfig{JWTSecret: "a-secret-of-at-least-32-characters"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := TokenOptions{Issuer: "gin-quickstart", Audience: []string{"gin-quickstart"}, TTL: 15 * time.Minute}
+	user := User{Role: RoleAdmin}
+	user.ID = 42
+
+	token, err := GenerateToken(user, "family", keys, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := VerifyToken(token, keys, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "42" || claims.Role != RoleAdmin || claims.SessionID != "family" || claims.RegisteredClaims.ID == "" {
+		t.Errorf("claims = %+v", claims)
+	}
+	if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime != opts.TTL {
+		t.Errorf("lifetime = %s, want %s", lifetime, opts.TTL)
+	}
+	if !claims.NotBefore.Equal(claims.IssuedAt.Time) {
+		t.Errorf("nbf = %s, iat = %s", claims.NotBefore, claims.IssuedAt)
+	}
+}
@@ -1,6 +1,9 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"log"
 	"strings"
@@ -12,11 +15,23 @@ import (
 // Mappers for ENV to nested config keys
 var mappers = map[string]string{
 	// App Configs
-	"APP_PORT":      "app.port",
-	"JWT_SECRET":    "app.jwt_secret",
-	"GIN_MODE":      "app.gin_mode",
-	"READ_TIMEOUT":  "app.read_timeout",
-	"WRITE_TIMEOUT": "app.write_timeout",
+	"APP_PORT":              "app.port",
+	"JWT_SECRET":            "app.jwt_secret",
+	"JWT_SIGNING_KEY":       "app.jwt_signing_key",
+	"JWT_VERIFICATION_KEYS": "app.jwt_verification_keys",
+	"JWT_ISSUER":            "app.jwt_issuer",
+	"JWT_AUDIENCE":          "app.jwt_audience",
+	"JWT_LEEWAY":            "app.jwt_leeway",
+	"ACCESS_TOKEN_TTL":      "app.access_token_ttl",
+	"INVITATION_TTL":        "app.invitation_ttl",
+	"ADMIN_USERNAME":        "app.admin_username",
+	"ADMIN_PASSWORD":        "app.admin_password",
+	"REFRESH_TOKEN_TTL":     "app.refresh_token_ttl",
+	"REVOCATION_CACHE_TTL":  "app.revocation_cache_ttl",
+	"CURSOR_SECRET":         "app.cursor_secret",
+	"GIN_MODE":              "app.gin_mode",
+	"READ_TIMEOUT":          "app.read_timeout",
+	"WRITE_TIMEOUT":         "app.write_timeout",
 
 	// DB Configs
 	"DB_HOST":     "db.host",
@@ -25,14 +40,46 @@ var mappers = map[string]string{
 	"DB_PASSWORD": "db.password",
 	"DB_NAME":     "db.name",
 	"SSL_MODE":    "db.ssl_mode",
+
+	// Search Configs
+	"SEARCH_SIMILARITY_THRESHOLD": "search.similarity_threshold",
+
+	// Albums Configs
+	"ALBUMS_REQUIRE_IF_MATCH":     "albums.require_if_match",
+	"ALBUMS_TRASH_RETENTION":      "albums.trash_retention",
+	"ALBUMS_TRASH_PURGE_INTERVAL": "albums.trash_purge_interval",
+
+	// HTTP Cache Configs
+	"CACHE_CONTROL_ALBUM_LIST":   "cache.album_list",
+	"CACHE_CONTROL_ALBUM_DETAIL": "cache.album_detail",
+	"CACHE_CONTROL_COVER":        "cache.cover",
+
+	// Blob Storage Configs
+	"STORAGE_DIR": "storage.dir",
+
+	// Cover Art Configs
+	"COVER_MAX_BYTES":       "covers.max_bytes",
+	"COVER_THUMBNAIL_SIZES": "covers.thumbnail_sizes",
 }
 
 type AppConfig struct {
-	Port         string        `mapstructure:"port"`
-	JWTSecret    string        `mapstructure:"jwt_secret"`
-	GinMode      string        `mapstructure:"gin_mode"`
-	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
-	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	Port                string        `mapstructure:"port"`
+	JWTSecret           string        `mapstructure:"jwt_secret"`
+	JWTSigningKey       string        `mapstructure:"jwt_signing_key"`
+	JWTVerificationKeys []string      `mapstructure:"jwt_verification_keys"`
+	JWTIssuer           string        `mapstructure:"jwt_issuer"`
+	JWTAudience         []string      `mapstructure:"jwt_audience"`
+	JWTLeeway           time.Duration `mapstructure:"jwt_leeway"`
+	AccessTokenTTL      time.Duration `mapstructure:"access_token_ttl"`
+	InvitationTTL       time.Duration `mapstructure:"invitation_ttl"`
+	AdminUsername       string        `mapstructure:"admin_username"`
+	AdminPassword       string        `mapstructure:"admin_password"`
+	RefreshTokenTTL     time.Duration `mapstructure:"refresh_token_ttl"`
+	RevocationCacheTTL  time.Duration `mapstructure:"revocation_cache_ttl"`
+	CursorSecret        string        `mapstructure:"cursor_secret"`
+	GinMode             string        `mapstructure:"gin_mode"`
+	ReadTimeout         time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout        time.Duration `mapstructure:"write_timeout"`
 }
 
 type DBConfig struct {
@@ -44,9 +91,39 @@ type DBConfig struct {
 	SSLMode  string `mapstructure:"ssl_mode"`
 }
 
+type SearchConfig struct {
+	SimilarityThreshold float64 `mapstructure:"similarity_threshold"`
+}
+
+type AlbumsConfig struct {
+	RequireIfMatch     bool          `mapstructure:"require_if_match"`
+	TrashRetention     time.Duration `mapstructure:"trash_retention"`
+	TrashPurgeInterval time.Duration `mapstructure:"trash_purge_interval"`
+}
+
+type CacheConfig struct {
+	AlbumList   string `mapstructure:"album_list"`
+	AlbumDetail string `mapstructure:"album_detail"`
+	Cover       string `mapstructure:"cover"`
+}
+
+type StorageConfig struct {
+	Dir string `mapstructure:"dir"`
+}
+
+type CoversConfig struct {
+	MaxBytes       int64 `mapstructure:"max_bytes"`
+	ThumbnailSizes []int `mapstructure:"thumbnail_sizes"`
+}
+
 type Config struct {
-	App AppConfig `mapstructure:"app"`
-	DB  DBConfig  `mapstructure:"db"`
+	App     AppConfig     `mapstructure:"app"`
+	DB      DBConfig      `mapstructure:"db"`
+	Search  SearchConfig  `mapstructure:"search"`
+	Albums  AlbumsConfig  `mapstructure:"albums"`
+	Cache   CacheConfig   `mapstructure:"cache"`
+	Storage StorageConfig `mapstructure:"storage"`
+	Covers  CoversConfig  `mapstructure:"covers"`
 }
 
 func LoadConfig() (cfg Config, err error) {
@@ -88,6 +165,27 @@ func LoadConfig() (cfg Config, err error) {
 	if !v.IsSet("app.write_timeout") {
 		v.Set("app.write_timeout", 10*time.Second)
 	}
+	if !v.IsSet("app.jwt_issuer") {
+		v.Set("app.jwt_issuer", "gin-quickstart")
+	}
+	if !v.IsSet("app.jwt_audience") {
+		v.Set("app.jwt_audience", []string{"gin-quickstart"})
+	}
+	if !v.IsSet("app.jwt_leeway") {
+		v.Set("app.jwt_leeway", 30*time.Second)
+	}
+	if !v.IsSet("app.access_token_ttl") {
+		v.Set("app.access_token_ttl", 15*time.Minute)
+	}
+	if !v.IsSet("app.invitation_ttl") {
+		v.Set("app.invitation_ttl", 72*time.Hour)
+	}
+	if !v.IsSet("app.refresh_token_ttl") {
+		v.Set("app.refresh_token_ttl", 30*24*time.Hour)
+	}
+	if !v.IsSet("app.revocation_cache_ttl") {
+		v.Set("app.revocation_cache_ttl", 30*time.Second)
+	}
 	if !v.IsSet("db.port") {
 		v.Set("db.port", "5432")
 	}
@@ -100,6 +198,39 @@ func LoadConfig() (cfg Config, err error) {
 	if !v.IsSet("app.gin_mode") {
 		v.Set("app.gin_mode", "debug")
 	}
+	if !v.IsSet("search.similarity_threshold") {
+		v.Set("search.similarity_threshold", 0.3)
+	}
+	if !v.IsSet("albums.require_if_match") {
+		v.Set("albums.require_if_match", true)
+	}
+	if !v.IsSet("albums.trash_retention") {
+		v.Set("albums.trash_retention", 30*24*time.Hour)
+	}
+	if !v.IsSet("albums.trash_purge_interval") {
+		v.Set("albums.trash_purge_interval", time.Hour)
+	}
+	if !v.IsSet("cache.album_list") {
+		v.Set("cache.album_list", "private, no-cache")
+	}
+	if !v.IsSet("cache.album_detail") {
+		v.Set("cache.album_detail", "private, max-age=60, must-revalidate")
+	}
+	if !v.IsSet("cache.cover") {
+		v.Set("cache.cover", "private, max-age=31536000, immutable")
+	}
+	if !v.IsSet("storage.dir") {
+		v.Set("storage.dir", "./data/blobs")
+	}
+	if !v.IsSet("covers.max_bytes") {
+		v.Set("covers.max_bytes", 10<<20)
+	}
+	if !v.IsSet("covers.thumbnail_sizes") {
+		v.Set("covers.thumbnail_sizes", []int{150, 300, 600})
+	}
+	if !v.IsSet("app.cursor_secret") && v.GetString("app.jwt_secret") != "" {
+		v.Set("app.cursor_secret", deriveKey(v.GetString("app.jwt_secret"), "gin-quickstart pagination cursor"))
+	}
 
 	// ---- 6. Unmarshal into nested struct ----
 	if err = v.Unmarshal(&cfg); err != nil {
@@ -110,6 +241,19 @@ func LoadConfig() (cfg Config, err error) {
 	if cfg.DB.User == "" || cfg.DB.Password == "" || cfg.DB.Name == "" {
 		return cfg, errors.New("missing DB_USER, DB_PASSWORD or DB_NAME")
 	}
+
+	// ---- 8. Validate the cursor signing key ----
+	if cfg.App.CursorSecret == "" {
+		return cfg, errors.New("missing CURSOR_SECRET (or JWT_SECRET to derive it from)")
+	}
 	log.Println("⚙️ Config loaded successfully (Nested struct + ENV mode)")
 	return cfg, nil
 }
+
+// deriveKey derives a key for purpose from secret, so that a secret shared
+// by several features never signs two of them with the same key.
+func deriveKey(secret, purpose string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return hex.EncodeToString(mac.Sum(nil))
+}
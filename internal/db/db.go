@@ -3,8 +3,11 @@ package db
 import (
 	"fmt"
 	"gin-quickstart/internal/albums"
+	"gin-quickstart/internal/artists"
 	"gin-quickstart/internal/auth"
 	"gin-quickstart/internal/config"
+	"gin-quickstart/internal/dberr"
+	"gin-quickstart/internal/genres"
 	"log"
 	"strings"
 
@@ -33,14 +36,32 @@ func InitDB(Cfg config.Config) (*gorm.DB, error) {
 
 	log.Println("💾 Database connection established successfully.")
 
+	// Translate driver errors (missing rows, constraint violations) into dberr sentinels.
+	if err := db.Use(dberr.Plugin{}); err != nil {
+		return nil, err
+	}
+
 	// Run AutoMigrate for the models.Album struct.
 	if err := db.AutoMigrate(
+		&artists.Artist{},
+		&genres.Genre{},
+		&albums.Tag{},
 		&albums.Album{},
+		&albums.Revision{},
+		&albums.Track{},
 		&auth.User{},
+		&auth.RefreshToken{},
+		&auth.RevokedToken{},
+		&auth.Invitation{},
 	); err != nil {
 		return nil, err
 	}
 
+	// Apply schema changes AutoMigrate cannot express (generated columns, special indexes).
+	if err := runMigrations(db); err != nil {
+		return nil, err
+	}
+
 	// Return the *gorm.DB instance and nil error, or nil and the error.
 	return db, nil
 }
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"gin-quickstart/internal/artists"
+
+	"gorm.io/gorm"
+)
+
+// migrations are raw SQL statements applied after AutoMigrate, in order.
+// Each one must be idempotent because they run on every start-up.
+var migrations = []string{
+	// Full-text search over album titles (weight A) and artists (weight B).
+	`ALTER TABLE albums ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(artist, '')), 'B')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_albums_search_vector ON albums USING GIN (search_vector)`,
+
+	// Trigram indexes for typo-tolerant (fuzzy) search.
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE INDEX IF NOT EXISTS idx_albums_title_trgm ON albums USING GIN (title gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_albums_artist_trgm ON albums USING GIN (artist gin_trgm_ops)`,
+
+	// Albums reference their artist; an artist with albums cannot be deleted.
+	`DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_albums_artist') THEN
+			ALTER TABLE albums ADD CONSTRAINT fk_albums_artist
+				FOREIGN KEY (artist_id) REFERENCES artists(id) ON DELETE RESTRICT;
+		END IF;
+	END $$`,
+}
+
+// runMigrations applies every entry of migrations inside a single transaction,
+// followed by the data migrations.
+func runMigrations(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range migrations {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return migrateAlbumArtists(tx)
+	})
+}
+
+// migrateAlbumArtists links albums that only have a free-text artist to an
+// artists row, merging spellings that normalize to the same name. The most
+// common spelling becomes the artist's canonical name. Albums that are
+// already linked are left alone, so this is a no-op once backfilled.
+func migrateAlbumArtists(tx *gorm.DB) error {
+	var names []string
+	err := tx.Table("albums").
+		Where("artist_id IS NULL").
+		Group("artist").
+		Order("COUNT(*) DESC, artist").
+		Pluck("artist", &names).Error
+	if err != nil {
+		return err
+	}
+
+	repo := artists.NewRepository(tx)
+	for _, name := range names {
+		normalized := artists.NormalizeName(name)
+		if normalized == "" {
+			continue
+		}
+		artist, err := repo.CreateIfMissing(artists.Artist{Name: name, NormalizedName: normalized})
+		if err != nil {
+			return err
+		}
+		err = tx.Exec("UPDATE albums SET artist_id = ?, artist = ? WHERE artist_id IS NULL AND artist = ?",
+			artist.ID, artist.Name, name).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
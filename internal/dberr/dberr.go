@@ -0,0 +1,112 @@
+// Package dberr translates database driver errors into sentinel errors, so
+// repositories and handlers can tell a duplicate key from a missing row
+// without parsing driver messages.
+package dberr
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when no row matches a lookup or write.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write violates a unique constraint.
+	ErrDuplicate = errors.New("record already exists")
+	// ErrForeignKey is returned when a write references a missing row, or
+	// removes a row other rows still reference.
+	ErrForeignKey = errors.New("record conflicts with related records")
+	// ErrInvalidValue is returned when a value violates a check or not-null
+	// constraint.
+	ErrInvalidValue = errors.New("value violates a database constraint")
+)
+
+// SQLSTATE codes of the constraint violations that are translated.
+const (
+	codeNotNullViolation    = "23502"
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
+	codeCheckViolation      = "23514"
+)
+
+// Error is a driver error translated to one of the sentinels. errors.Is
+// matches both the sentinel and the original error.
+type Error struct {
+	// Kind is the sentinel, such as ErrDuplicate.
+	Kind error
+	// Constraint names the violated constraint, if the driver reported one.
+	Constraint string
+
+	cause error
+}
+
+func (e *Error) Error() string {
+	return e.Kind.Error()
+}
+
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.cause}
+}
+
+// Translate maps err to an *Error when it is a missing row or a constraint
+// violation, and returns it unchanged otherwise.
+func Translate(err error) error {
+	if err == nil {
+		return nil
+	}
+	var translated *Error
+	if errors.As(err, &translated) || errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, sql.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, cause: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	var kind error
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		kind = ErrDuplicate
+	case codeForeignKeyViolation:
+		kind = ErrForeignKey
+	case codeCheckViolation, codeNotNullViolation:
+		kind = ErrInvalidValue
+	default:
+		return err
+	}
+	return &Error{Kind: kind, Constraint: pgErr.ConstraintName, cause: err}
+}
+
+// Plugin translates the error of every GORM operation with Translate, so
+// repositories return sentinels without wrapping each call.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "dberr"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	translate := func(tx *gorm.DB) {
+		tx.Error = Translate(tx.Error)
+	}
+	cb := db.Callback()
+	for _, register := range []func() error{
+		func() error { return cb.Create().After("*").Register("dberr:translate", translate) },
+		func() error { return cb.Query().After("*").Register("dberr:translate", translate) },
+		func() error { return cb.Update().After("*").Register("dberr:translate", translate) },
+		func() error { return cb.Delete().After("*").Register("dberr:translate", translate) },
+		func() error { return cb.Row().After("*").Register("dberr:translate", translate) },
+		func() error { return cb.Raw().After("*").Register("dberr:translate", translate) },
+	} {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
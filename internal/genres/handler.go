@@ -0,0 +1,180 @@
+package genres
+
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/problem"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler holds the necessary dependencies for the genre handlers.
+type Handler struct {
+	service Service
+}
+
+// NewHandler is the constructor for Handler.
+func NewHandler(s Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RegisterRoutes attaches genre routes to the Gin engine.
+func (h *Handler) RegisterRoutes(g *gin.RouterGroup) {
+	genreGroup := g.Group("/genres")
+	{
+		// 1. READ routes (accessible to anyone with a valid token: 'user' or 'admin')
+		genreGroup.GET("/", h.GetGenres)
+		genreGroup.GET("/:id", h.GetGenreByID)
+
+		// 2. WRITE routes (only accessible to 'admin')
+		adminGenreGroup := genreGroup.Group("/")
+		adminGenreGroup.Use(middleware.Authorize("admin"))
+		{
+			adminGenreGroup.POST("/", h.CreateGenre)
+			adminGenreGroup.PUT("/:id", h.UpdateGenre)
+			adminGenreGroup.DELETE("/:id", h.DeleteGenre)
+		}
+	}
+}
+
+// GetGenres lists the whole genre vocabulary alphabetically.
+func (h *Handler) GetGenres(c *gin.Context) {
+	// 1. Call service to get all genres
+	genres, err := h.service.FindAll()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 2. Return genres
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"genres": genres,
+		},
+		"message": "Genres retrieved successfully",
+	})
+}
+
+// GetGenreByID retrieves a single genre by its ID.
+func (h *Handler) GetGenreByID(c *gin.Context) {
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Call service to find by ID
+	genre, err := h.service.FindById(uint(idUint))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 3. Return found genre
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"genre": genre,
+		},
+		"message": "Genre retrieved successfully",
+	})
+}
+
+// CreateGenre processes a POST request to add a genre to the vocabulary.
+func (h *Handler) CreateGenre(c *gin.Context) {
+	var genre Genre
+
+	// 1. Bind JSON body to genre struct
+	if err := c.ShouldBindJSON(&genre); err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	// 2. Call service to create genre
+	created, err := h.service.Create(genre)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 3. Return created genre with 201 Created status
+	c.JSON(http.StatusCreated, gin.H{
+		"data": gin.H{
+			"genre": created,
+		},
+		"message": "Genre created successfully",
+	})
+}
+
+// UpdateGenre renames a genre, which also changes its slug.
+func (h *Handler) UpdateGenre(c *gin.Context) {
+	var genre Genre
+
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Bind JSON body to genre struct
+	if err := c.ShouldBindJSON(&genre); err != nil {
+		c.Error(problem.New(http.StatusBadRequest, err.Error()))
+		return
+	}
+	genre.ID = uint(idUint)
+
+	// 3. Call service to update
+	updated, err := h.service.Update(genre)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 4. Return updated genre
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"genre": updated,
+		},
+		"message": "Genre updated successfully",
+	})
+}
+
+// DeleteGenre removes a genre that is no longer assigned to any album.
+func (h *Handler) DeleteGenre(c *gin.Context) {
+	// 1. Convert string URL param to uint
+	idUint, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.Error(problem.New(http.StatusBadRequest, "Invalid ID format. Must be an integer."))
+		return
+	}
+
+	// 2. Call service to delete
+	if err := h.service.Delete(uint(idUint)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 3. Return no content status
+	c.Status(http.StatusNoContent)
+}
+
+// ErrorStatuses maps the genre domain errors to HTTP statuses for
+// middleware.Problems.
+var ErrorStatuses = []problem.Mapping{
+	{Err: ErrInvalidName, Status: http.StatusUnprocessableEntity},
+	{Err: ErrDuplicateGenre, Status: http.StatusConflict},
+	{Err: ErrGenreInUse, Status: http.StatusConflict},
+}
+
+// respondError reports a failed genre request; a missing record is named
+// as the genre.
+func respondError(c *gin.Context, err error) {
+	if errors.Is(err, dberr.ErrNotFound) {
+		err = problem.New(http.StatusNotFound, "genre not found")
+	}
+	c.Error(err)
+}
@@ -0,0 +1,26 @@
+package genres
+
+import (
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Genre is an entry in the admin-managed genre vocabulary.
+type Genre struct {
+	Name string `json:"name" binding:"required"`
+	// Slug identifies the genre in album filters (?genre=hard-bop).
+	Slug string `json:"slug" gorm:"uniqueIndex;not null"`
+	gorm.Model
+}
+
+// Slugify derives a genre's slug from its name: lower case, "&" spelled as
+// "and", and runs of anything but letters and digits turned into a hyphen.
+func Slugify(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "&", " and "))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
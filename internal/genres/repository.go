@@ -0,0 +1,112 @@
+package genres
+
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+
+	"gorm.io/gorm"
+)
+
+// Repository defines the interface for data access methods.
+type Repository interface {
+	FindAll() ([]Genre, error)
+	FindById(id uint) (Genre, error)
+	FindBySlug(slug string) (Genre, error)
+	FindBySlugs(slugs []string) ([]Genre, error)
+	Create(genre Genre) (Genre, error)
+	Update(genre Genre) (Genre, error)
+	Delete(id uint) error
+	CountAlbums(id uint) (int64, error)
+}
+
+// repository is the concrete implementation of the Repository interface.
+type repository struct {
+	DB *gorm.DB
+}
+
+// NewRepository is the constructor for the concrete repository.
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{DB: db}
+}
+
+func (r *repository) FindAll() ([]Genre, error) {
+	var genres []Genre
+	if err := r.DB.Order("name ASC").Order("id ASC").Find(&genres).Error; err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
+
+func (r *repository) FindById(id uint) (Genre, error) {
+	var genre Genre
+	if err := r.DB.First(&genre, "id = ?", id).Error; err != nil {
+		return Genre{}, err
+	}
+	return genre, nil
+}
+
+func (r *repository) FindBySlug(slug string) (Genre, error) {
+	var genre Genre
+	if err := r.DB.First(&genre, "slug = ?", slug).Error; err != nil {
+		return Genre{}, err
+	}
+	return genre, nil
+}
+
+func (r *repository) FindBySlugs(slugs []string) ([]Genre, error) {
+	var genres []Genre
+	if len(slugs) == 0 {
+		return genres, nil
+	}
+	if err := r.DB.Where("slug IN ?", slugs).Order("name ASC").Find(&genres).Error; err != nil {
+		return nil, err
+	}
+	return genres, nil
+}
+
+func (r *repository) Create(genre Genre) (Genre, error) {
+	if err := r.DB.Create(&genre).Error; err != nil {
+		// The unique index on slug catches races ensureUnique misses.
+		if errors.Is(err, dberr.ErrDuplicate) {
+			return Genre{}, ErrDuplicateGenre
+		}
+		return Genre{}, err
+	}
+	return genre, nil
+}
+
+func (r *repository) Update(genre Genre) (Genre, error) {
+	result := r.DB.Model(&genre).Select("name", "slug").Updates(genre)
+	if errors.Is(result.Error, dberr.ErrDuplicate) {
+		return Genre{}, ErrDuplicateGenre
+	}
+	if result.Error != nil {
+		return Genre{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Genre{}, dberr.ErrNotFound
+	}
+	return r.FindById(genre.ID)
+}
+
+// Delete removes the genre permanently so its slug can be reused.
+func (r *repository) Delete(id uint) error {
+	result := r.DB.Unscoped().Delete(&Genre{}, "id = ?", id)
+	if errors.Is(result.Error, dberr.ErrForeignKey) {
+		return ErrGenreInUse
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return dberr.ErrNotFound
+	}
+	return nil
+}
+
+// CountAlbums counts the albums, trashed ones included, classified under the genre.
+func (r *repository) CountAlbums(id uint) (int64, error) {
+	var count int64
+	err := r.DB.Table("album_genres").Where("genre_id = ?", id).Count(&count).Error
+	return count, err
+}
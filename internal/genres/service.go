@@ -0,0 +1,111 @@
+package genres
+
+import (
+	"errors"
+	"gin-quickstart/internal/dberr"
+	"strings"
+)
+
+var (
+	// ErrInvalidName is returned for a name with no letters or digits.
+	ErrInvalidName = errors.New("genre name must contain at least one letter or digit")
+	// ErrDuplicateGenre is returned when another genre already has the same slug.
+	ErrDuplicateGenre = errors.New("a genre with an equivalent name already exists")
+	// ErrGenreInUse is returned when deleting a genre that albums are still classified under.
+	ErrGenreInUse = errors.New("genre is still assigned to albums")
+)
+
+// Service defines the methods for business logic.
+type Service interface {
+	FindAll() ([]Genre, error)
+	FindById(id uint) (Genre, error)
+	FindBySlugs(slugs []string) ([]Genre, error)
+	Create(genre Genre) (Genre, error)
+	Update(genre Genre) (Genre, error)
+	Delete(id uint) error
+}
+
+// service is the concrete implementation of Service.
+type service struct {
+	repo Repository
+}
+
+// NewService is the constructor.
+func NewService(r Repository) Service {
+	return &service{repo: r}
+}
+
+func (s *service) FindAll() ([]Genre, error) {
+	return s.repo.FindAll()
+}
+
+func (s *service) FindById(id uint) (Genre, error) {
+	return s.repo.FindById(id)
+}
+
+func (s *service) FindBySlugs(slugs []string) ([]Genre, error) {
+	return s.repo.FindBySlugs(slugs)
+}
+
+func (s *service) Create(genre Genre) (Genre, error) {
+	genre, err := prepare(genre)
+	if err != nil {
+		return Genre{}, err
+	}
+	if err := s.ensureUnique(genre); err != nil {
+		return Genre{}, err
+	}
+	genre.ID = 0
+	return s.repo.Create(genre)
+}
+
+func (s *service) Update(genre Genre) (Genre, error) {
+	genre, err := prepare(genre)
+	if err != nil {
+		return Genre{}, err
+	}
+	if err := s.ensureUnique(genre); err != nil {
+		return Genre{}, err
+	}
+	return s.repo.Update(genre)
+}
+
+// Delete removes a genre that no album is classified under.
+func (s *service) Delete(id uint) error {
+	if _, err := s.repo.FindById(id); err != nil {
+		return err
+	}
+	count, err := s.repo.CountAlbums(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrGenreInUse
+	}
+	return s.repo.Delete(id)
+}
+
+// ensureUnique rejects genre if a different genre has the same slug.
+func (s *service) ensureUnique(genre Genre) error {
+	existing, err := s.repo.FindBySlug(genre.Slug)
+	if errors.Is(err, dberr.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != genre.ID {
+		return ErrDuplicateGenre
+	}
+	return nil
+}
+
+// prepare trims the display name and derives the slug.
+func prepare(genre Genre) (Genre, error) {
+	genre.Name = strings.TrimSpace(genre.Name)
+	genre.Slug = Slugify(genre.Name)
+	if genre.Slug == "" {
+		return Genre{}, ErrInvalidName
+	}
+	return genre, nil
+}
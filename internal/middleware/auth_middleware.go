@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"gin-quickstart/internal/auth"
+	"gin-quickstart/internal/problem"
 	"net/http"
 	"strings"
 
@@ -9,45 +10,62 @@ import (
 )
 
 const BearerSchema = "Bearer "
-const contextClaimsKey = "claims"
 
-func AuthMiddleware(secret []byte) gin.HandlerFunc {
+// AuthMiddleware accepts requests carrying a valid access token that has not
+// been revoked.
+func AuthMiddleware(keys *auth.KeySet, opts auth.TokenOptions, revocations auth.Revocations) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), BearerSchema)
 
-		claims, err := auth.VerifyToken(tokenString, secret)
+		claims, err := auth.VerifyToken(tokenString, keys, opts)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or missing token"})
+			abortWithProblem(c, problem.New(http.StatusUnauthorized, "Invalid or missing token"))
+			return
+		}
+		// Reject tokens revoked by logout or by revoking the user's sessions
+		revoked, err := revocations.IsRevoked(claims)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		if revoked {
+			abortWithProblem(c, problem.New(http.StatusUnauthorized, "Token has been revoked"))
 			return
 		}
 		// Store claims in context for further handlers to use
-		c.Set(contextClaimsKey, claims)
+		c.Set(auth.ClaimsContextKey, claims)
 		// Proceed to the next handler
 		c.Next()
 	}
 }
 
+// CurrentClaims returns the JWT claims stored by AuthMiddleware, if any.
+func CurrentClaims(c *gin.Context) (*auth.Claims, bool) {
+	return auth.ContextClaims(c)
+}
+
 func Authorize(requiredRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1. Get the Claims from the context (set by AuthMiddleware)
-		claimsRaw, exists := c.Get(contextClaimsKey)
+		claimsRaw, exists := c.Get(auth.ClaimsContextKey)
 
 		// Safety check (shouldn't happen if AuthMiddleware ran)
 		if !exists {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied. Claims missing."})
+			abortWithProblem(c, problem.New(http.StatusForbidden, "Access denied. Claims missing."))
 			return
 		}
 
 		// 2. Cast the claims back to the *auth.Claims type
 		claims, ok := claimsRaw.(*auth.Claims)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied. Claims malformed."})
+			abortWithProblem(c, problem.New(http.StatusForbidden, "Access denied. Claims malformed."))
 			return
 		}
 
 		// 3. Check for role match
 		if claims.Role != requiredRole {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden. Insufficient role privileges."})
+			abortWithProblem(c, problem.New(http.StatusForbidden, "Forbidden. Insufficient role privileges."))
 			return
 		}
 
@@ -0,0 +1,30 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// CacheControl sets the Cache-Control header on successful responses of the
+// route it is attached to. Error responses are never marked cacheable, and
+// an empty policy leaves the header unset.
+func CacheControl(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if policy == "" {
+			c.Next()
+			return
+		}
+		c.Header("Cache-Control", policy)
+		c.Writer = &cacheControlWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// cacheControlWriter drops the Cache-Control header when an error status is written.
+type cacheControlWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *cacheControlWriter) WriteHeader(code int) {
+	if code >= 400 {
+		w.Header().Del("Cache-Control")
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"gin-quickstart/internal/dberr"
+	"gin-quickstart/internal/problem"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMappings apply to every route, after the feature packages' own.
+// They cover the database errors repositories did not turn into domain
+// errors.
+var defaultMappings = []problem.Mapping{
+	{Err: dberr.ErrNotFound, Status: http.StatusNotFound},
+	{Err: dberr.ErrDuplicate, Status: http.StatusConflict},
+	{Err: dberr.ErrForeignKey, Status: http.StatusConflict},
+	{Err: dberr.ErrInvalidValue, Status: http.StatusUnprocessableEntity},
+}
+
+// Problems renders the last error a handler reported with c.Error as an
+// RFC 7807 application/problem+json response, unless a response was already
+// written. Errors matching mappings get the mapped status and their own
+// message; any other error is a 500, logged with the request ID, whose
+// message is hidden in release mode.
+func Problems(mappings ...[]problem.Mapping) gin.HandlerFunc {
+	var all []problem.Mapping
+	for _, m := range mappings {
+		all = append(all, m...)
+	}
+	all = append(all, defaultMappings...)
+
+	return func(c *gin.Context) {
+		c.Next()
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		p := problem.From(err, all, gin.Mode() != gin.ReleaseMode)
+		if p.Status >= http.StatusInternalServerError {
+			log.Printf("request %s: %s %s: %v", CurrentRequestID(c), c.Request.Method, c.Request.URL.Path, err)
+		}
+		writeProblem(c, p)
+	}
+}
+
+// Recover answers a request whose handler panicked with a 500 problem. It
+// is meant for gin.CustomRecovery, which logs the panic.
+func Recover(c *gin.Context, recovered any) {
+	err := fmt.Errorf("panic: %v", recovered)
+	writeProblem(c, problem.From(err, nil, gin.Mode() != gin.ReleaseMode))
+	c.Abort()
+}
+
+// NoRoute answers requests for unknown paths with a 404 problem.
+func NoRoute(c *gin.Context) {
+	c.Error(problem.New(http.StatusNotFound, "no route for "+c.Request.Method+" "+c.Request.URL.Path))
+}
+
+// abortWithProblem stops the handler chain and reports p.
+func abortWithProblem(c *gin.Context, p *problem.Problem) {
+	c.Error(p)
+	c.Abort()
+}
+
+// writeProblem fills in the request details and renders p.
+func writeProblem(c *gin.Context, p *problem.Problem) {
+	p.Instance = c.Request.URL.Path
+	p.RequestID = CurrentRequestID(c)
+	c.Header("Content-Type", problem.ContentType)
+	c.JSON(p.Status, p)
+}
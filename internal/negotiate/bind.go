@@ -0,0 +1,127 @@
+package negotiate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/goccy/go-yaml/token"
+	"github.com/ugorji/go/codec"
+)
+
+// ErrUnsupportedMediaType is returned by Bind for a body in a format it
+// cannot read.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// MaxBodyBytes limits the size of the request bodies Bind reads.
+const MaxBodyBytes = 1 << 20
+
+// ErrBodyTooLarge is returned by Bind for a body over MaxBodyBytes.
+var ErrBodyTooLarge = fmt.Errorf("request body exceeds %d bytes", MaxBodyBytes)
+
+// Bind decodes the request body into obj according to its Content-Type
+// and validates it, like ShouldBindJSON does for JSON. A body without a
+// Content-Type is read as JSON. CSV bodies hold a header row and a single
+// record, with list fields separated by "|". Bodies are limited to
+// MaxBodyBytes, and YAML bodies must not use aliases.
+func Bind(c *gin.Context, obj any) error {
+	contentType := c.ContentType()
+	if contentType == "" {
+		contentType = string(JSON)
+	}
+	format, ok := aliases[contentType]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnsupportedMediaType, contentType)
+	}
+	if c.Request.Body == nil {
+		return errors.New("invalid request: empty body")
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return ErrBodyTooLarge
+		}
+		return err
+	}
+	if format == JSON {
+		return binding.JSON.BindBody(body, obj)
+	}
+
+	tree, err := decodeBody(format, body)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(coerce(tree, reflect.TypeOf(obj)))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// BindStatus is the response status for an error returned by Bind.
+func BindStatus(err error) int {
+	if errors.Is(err, ErrUnsupportedMediaType) {
+		return http.StatusUnsupportedMediaType
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// decodeBody reads a non-JSON body into a tree of maps, slices and scalars.
+func decodeBody(format Format, data []byte) (any, error) {
+	switch format {
+	case XML:
+		return decodeXML(bytes.NewReader(data))
+	case CSV:
+		return decodeCSV(bytes.NewReader(data))
+	case YAML:
+		if err := rejectYAMLAliases(data); err != nil {
+			return nil, err
+		}
+		var tree any
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	case MsgPack:
+		handle := &codec.MsgpackHandle{}
+		handle.MapType = reflect.TypeFor[map[string]any]()
+		handle.RawToString = true
+		var tree any
+		if err := codec.NewDecoderBytes(data, handle).Decode(&tree); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedMediaType, format)
+	}
+}
+
+// rejectYAMLAliases refuses documents that use aliases. Nested aliases
+// expand exponentially when decoded ("billion laughs"), and no request body
+// needs them.
+func rejectYAMLAliases(data []byte) error {
+	for _, tk := range lexer.Tokenize(string(data)) {
+		if tk.Type == token.AliasType {
+			return errors.New("YAML aliases are not allowed in request bodies")
+		}
+	}
+	return nil
+}
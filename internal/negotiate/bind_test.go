@@ -0,0 +1,104 @@
+package negotiate
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ugorji/go/codec"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type bindTarget struct {
+	Title string   `json:"title" binding:"required"`
+	Year  int      `json:"year"`
+	Tags  []string `json:"tags"`
+}
+
+func bindRequest(contentType string, body []byte) (*gin.Context, error) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	if contentType != "" {
+		c.Request.Header.Set("Content-Type", contentType)
+	}
+	var got bindTarget
+	err := Bind(c, &got)
+	c.Set("bound", got)
+	return c, err
+}
+
+func msgpackBody(t *testing.T, v any) []byte {
+	t.Helper()
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, new(codec.MsgpackHandle)).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestBind(t *testing.T) {
+	want := bindTarget{Title: "Blue Train", Year: 1957, Tags: []string{"jazz", "hard bop"}}
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        bindTarget
+		status      int
+	}{
+		{"JSON", "application/json", []byte(`{"title":"Blue Train","year":1957,"tags":["jazz","hard bop"]}`), want, 0},
+		{"no content type is JSON", "", []byte(`{"title":"Blue Train","year":1957,"tags":["jazz","hard bop"]}`), want, 0},
+		{"XML", "application/xml", []byte(`<album><title>Blue Train</title><year>1957</year><tags><item>jazz</item><item>hard bop</item></tags></album>`), want, 0},
+		{"YAML", "application/x-yaml", []byte("title: Blue Train\nyear: 1957\ntags: [jazz, hard bop]\n"), want, 0},
+		{"MsgPack", "application/msgpack", msgpackBody(t, map[string]any{"title": "Blue Train", "year": 1957, "tags": []string{"jazz", "hard bop"}}), want, 0},
+		{"CSV", "text/csv; charset=utf-8", []byte("title,year,tags\nBlue Train,1957,jazz|hard bop\n"), want, 0},
+		{"unsupported media type", "text/plain", []byte("Blue Train"), bindTarget{}, http.StatusUnsupportedMediaType},
+		{"validation fails", "application/yaml", []byte("year: 1957\n"), bindTarget{}, http.StatusBadRequest},
+		{"malformed JSON", "application/json", []byte(`{"title":`), bindTarget{}, http.StatusBadRequest},
+		{"CSV with two records", "text/csv", []byte("title\nA\nB\n"), bindTarget{}, http.StatusBadRequest},
+		{"YAML alias", "application/yaml", []byte("x: &a Blue Train\ntitle: *a\n"), bindTarget{}, http.StatusBadRequest},
+		{"YAML merge key", "application/yaml", []byte("base: &b {title: Blue Train}\n<<: *b\n"), bindTarget{}, http.StatusBadRequest},
+		{"JSON over the limit", "application/json", []byte(`{"title":"` + strings.Repeat("a", MaxBodyBytes) + `"}`), bindTarget{}, http.StatusRequestEntityTooLarge},
+		{"YAML over the limit", "application/yaml", []byte("title: " + strings.Repeat("a", MaxBodyBytes) + "\n"), bindTarget{}, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := bindRequest(tt.contentType, tt.body)
+			if tt.status != 0 {
+				if err == nil {
+					t.Fatalf("Bind succeeded, want status %d", tt.status)
+				}
+				if got := BindStatus(err); got != tt.status {
+					t.Fatalf("BindStatus(%v) = %d, want %d", err, got, tt.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Bind: %v", err)
+			}
+			if got := c.MustGet("bound"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Bind = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRejectYAMLAliasesBillionLaughs(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("a0: &a0 [lol, lol, lol, lol, lol, lol, lol, lol, lol]\n")
+	for i := 1; i < 10; i++ {
+		ref := fmt.Sprintf("*a%d", i-1)
+		fmt.Fprintf(&b, "a%d: &a%d [%s]\n", i, i, strings.Join([]string{ref, ref, ref, ref, ref, ref, ref, ref, ref}, ", "))
+	}
+	if _, err := decodeBody(YAML, []byte(b.String())); err == nil || errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("decodeBody = %v, want an alias error", err)
+	}
+}
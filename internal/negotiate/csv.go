@@ -0,0 +1,132 @@
+package negotiate
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Record is implemented by types that can be written as a CSV row. The
+// header of a list is taken from its first element, or from the zero value
+// when the list is empty.
+type Record interface {
+	CSVHeader() []string
+	CSVRecord() []string
+}
+
+var recordType = reflect.TypeFor[Record]()
+
+var csvContentType = []string{"text/csv; charset=utf-8"}
+
+// csvTable renders a header row followed by one row per record.
+type csvTable struct {
+	header []string
+	rows   []Record
+}
+
+func (t csvTable) WriteContentType(w http.ResponseWriter) {
+	if header := w.Header(); len(header["Content-Type"]) == 0 {
+		header["Content-Type"] = csvContentType
+	}
+}
+
+func (t csvTable) Render(w http.ResponseWriter) error {
+	t.WriteContentType(w)
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.header); err != nil {
+		return err
+	}
+	for _, r := range t.rows {
+		if err := cw.Write(r.CSVRecord()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// tableOf finds the rows of a response: a Record or a slice of Records,
+// either directly or as the only such value in the "data" object of the
+// usual {"data": ..., "message": ...} envelope. Other values in the
+// envelope, such as pagination, are left out.
+func tableOf(obj any) (csvTable, bool) {
+	if t, ok := asTable(obj); ok {
+		return t, true
+	}
+	envelope, ok := obj.(gin.H)
+	if !ok {
+		return csvTable{}, false
+	}
+	data, ok := envelope["data"].(gin.H)
+	if !ok {
+		return asTable(envelope["data"])
+	}
+	var (
+		found csvTable
+		n     int
+	)
+	for _, v := range data {
+		if t, ok := asTable(v); ok {
+			found = t
+			n++
+		}
+	}
+	return found, n == 1
+}
+
+// asTable converts a Record or a slice of Records.
+func asTable(v any) (csvTable, bool) {
+	if r, ok := v.(Record); ok {
+		return csvTable{header: r.CSVHeader(), rows: []Record{r}}, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || !rv.Type().Elem().Implements(recordType) {
+		return csvTable{}, false
+	}
+	t := csvTable{rows: make([]Record, rv.Len())}
+	for i := range t.rows {
+		t.rows[i] = rv.Index(i).Interface().(Record)
+	}
+	if len(t.rows) > 0 {
+		t.header = t.rows[0].CSVHeader()
+	} else {
+		t.header = reflect.Zero(rv.Type().Elem()).Interface().(Record).CSVHeader()
+	}
+	return t, true
+}
+
+// decodeCSV reads a body made of a header row and a single record into an
+// object keyed by the header names.
+func decodeCSV(r io.Reader) (any, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV body must have a header row and one record")
+	}
+	if err != nil {
+		return nil, err
+	}
+	record, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("CSV body must have a header row and one record")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cr.Read(); err != io.EOF {
+		return nil, errors.New("CSV body must hold exactly one record")
+	}
+
+	obj := make(map[string]any, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		obj[name] = record[i]
+	}
+	return obj, nil
+}
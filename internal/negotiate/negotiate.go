@@ -0,0 +1,242 @@
+// Package negotiate picks the response format from the Accept header and
+// the request body format from Content-Type. JSON is the default; XML,
+// YAML and MessagePack carry the same fields under the same names, and CSV
+// is available for responses made of rows (see Record).
+package negotiate
+
+import (
+	"gin-quickstart/internal/problem"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+)
+
+// Format is a supported representation, named by its canonical media type.
+type Format string
+
+// Supported formats.
+const (
+	JSON    Format = "application/json"
+	XML     Format = "application/xml"
+	YAML    Format = "application/yaml"
+	MsgPack Format = "application/msgpack"
+	CSV     Format = "text/csv"
+)
+
+// Structured are the formats that can represent any response.
+var Structured = []Format{JSON, XML, YAML, MsgPack}
+
+// Tabular are the formats offered for responses made of rows.
+var Tabular = []Format{JSON, XML, YAML, MsgPack, CSV}
+
+// aliases maps other common media types to their format.
+var aliases = map[string]Format{
+	"application/json":        JSON,
+	"application/xml":         XML,
+	"text/xml":                XML,
+	"application/yaml":        YAML,
+	"application/x-yaml":      YAML,
+	"text/yaml":               YAML,
+	"application/msgpack":     MsgPack,
+	"application/x-msgpack":   MsgPack,
+	"application/vnd.msgpack": MsgPack,
+	"text/csv":                CSV,
+}
+
+// contextFormatKey stores the negotiated choice in the gin context.
+const contextFormatKey = "negotiate.format"
+
+// choice is a negotiated format and the media type the client asked for it
+// by, which may be an alias such as text/xml.
+type choice struct {
+	format    Format
+	mediaType string
+}
+
+// Offer negotiates the response format of the routes it is attached to
+// among formats, in order of preference. Requests accepting none of them
+// are answered with 406 Not Acceptable before the handler runs.
+func Offer(formats ...Format) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chosen, ok := choose(c.GetHeader("Accept"), formats)
+		if !ok {
+			types := make([]string, len(formats))
+			for i, f := range formats {
+				types[i] = string(f)
+			}
+			c.Error(problem.New(http.StatusNotAcceptable, "Supported media types: "+strings.Join(types, ", ")))
+			c.Abort()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept")
+		c.Set(contextFormatKey, chosen)
+		c.Next()
+	}
+}
+
+// Choose returns the offered format the Accept header prefers, by quality
+// value and then by the order of offered. An empty header accepts the first
+// offer. It reports false if no offer is acceptable.
+func Choose(accept string, offered []Format) (Format, bool) {
+	chosen, ok := choose(accept, offered)
+	return chosen.format, ok
+}
+
+func choose(accept string, offered []Format) (choice, bool) {
+	if len(offered) == 0 {
+		return choice{}, false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return choice{offered[0], string(offered[0])}, true
+	}
+	ranges := parseAccept(accept)
+
+	var (
+		best  choice
+		bestQ float64
+	)
+	for _, offer := range offered {
+		if mediaType, q := quality(ranges, offer); q > bestQ {
+			best, bestQ = choice{offer, mediaType}, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// mediaRange is one entry of an Accept header.
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+// specificity ranks exact types above type/* above */*.
+func (r mediaRange) specificity() int {
+	switch {
+	case r.typ == "*":
+		return 0
+	case r.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (r mediaRange) matches(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	return (r.typ == "*" || r.typ == typ) && (r.subtype == "*" || r.subtype == subtype)
+}
+
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.EqualFold(k, "q") {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	// Most specific first, so the first match is the one that applies.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].specificity() > ranges[j].specificity()
+	})
+	return ranges
+}
+
+// quality is the q value the most specific matching range gives format,
+// and the media type it matched. The canonical type is tried first, then
+// the aliases.
+func quality(ranges []mediaRange, format Format) (string, float64) {
+	mediaTypes := []string{string(format)}
+	for alias, f := range aliases {
+		if f == format && alias != string(format) {
+			mediaTypes = append(mediaTypes, alias)
+		}
+	}
+	sort.Strings(mediaTypes[1:])
+
+	bestType, bestQ, bestSpec := "", 0.0, -1
+	for _, mediaType := range mediaTypes {
+		for _, r := range ranges {
+			if !r.matches(mediaType) {
+				continue
+			}
+			if spec := r.specificity(); spec > bestSpec || spec == bestSpec && r.q > bestQ {
+				bestType, bestQ, bestSpec = mediaType, r.q, spec
+			}
+			break
+		}
+	}
+	return bestType, bestQ
+}
+
+// MediaType returns the media type negotiated for the response, such as
+// text/xml, so handlers can tell the representations of a resource apart,
+// e.g. in entity tags.
+func MediaType(c *gin.Context) string {
+	return negotiated(c).mediaType
+}
+
+// negotiated is the choice made by Offer, or without Offer the structured
+// format the client prefers, defaulting to JSON.
+func negotiated(c *gin.Context) choice {
+	if v, ok := c.Get(contextFormatKey); ok {
+		return v.(choice)
+	}
+	if v, ok := choose(c.GetHeader("Accept"), Structured); ok {
+		return v
+	}
+	return choice{JSON, string(JSON)}
+}
+
+// Render writes obj with the given status in the format negotiated by
+// Offer. Without Offer, or when the response cannot be written as CSV, it
+// falls back to the structured format the client prefers, and to JSON if it
+// accepts none. Errors are not rendered here but reported with c.Error.
+func Render(c *gin.Context, status int, obj any) {
+	chosen := negotiated(c)
+	if chosen.format == CSV {
+		if table, ok := tableOf(obj); ok {
+			c.Render(status, table)
+			return
+		}
+		chosen = choice{JSON, string(JSON)}
+		if v, ok := choose(c.GetHeader("Accept"), Structured); ok {
+			chosen = v
+		}
+	}
+
+	if chosen.format == JSON {
+		c.JSON(status, obj)
+		return
+	}
+	tree, err := toTree(obj)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if chosen.mediaType != string(chosen.format) {
+		c.Header("Content-Type", chosen.mediaType+"; charset=utf-8")
+	}
+	switch chosen.format {
+	case XML:
+		c.Render(status, xmlTree{data: tree})
+	case YAML:
+		c.Render(status, render.YAML{Data: tree})
+	case MsgPack:
+		c.Render(status, render.MsgPack{Data: tree})
+	}
+}
@@ -0,0 +1,42 @@
+package negotiate
+
+import "testing"
+
+func TestChoose(t *testing.T) {
+	tests := []struct {
+		name      string
+		accept    string
+		offered   []Format
+		want      Format
+		mediaType string
+		ok        bool
+	}{
+		{"no header picks the first offer", "", Structured, JSON, "application/json", true},
+		{"exact type", "application/yaml", Structured, YAML, "application/yaml", true},
+		{"alias is remembered", "text/xml", Structured, XML, "text/xml", true},
+		{"highest q wins", "application/xml;q=0.5, application/msgpack", Structured, MsgPack, "application/msgpack", true},
+		{"wildcard picks the first offer", "*/*", Structured, JSON, "application/json", true},
+		{"specific range overrides wildcard", "*/*;q=0.1, application/json;q=0", Structured, XML, "application/xml", true},
+		{"type wildcard matches aliases", "text/*", Tabular, XML, "text/xml", true},
+		{"type wildcard", "text/*", []Format{JSON, CSV}, CSV, "text/csv", true},
+		{"q=0 excludes", "application/json;q=0", []Format{JSON}, "", "", false},
+		{"nothing acceptable", "image/png", Structured, "", "", false},
+		{"CSV only for tabular offers", "text/csv", Structured, "", "", false},
+		{"malformed entries are skipped", "garbage, application/yaml", Structured, YAML, "application/yaml", true},
+		{"no offers", "*/*", nil, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := choose(tt.accept, tt.offered)
+			if ok != tt.ok {
+				t.Fatalf("choose(%q) ok = %v, want %v", tt.accept, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if got.format != tt.want || got.mediaType != tt.mediaType {
+				t.Errorf("choose(%q) = %s (%s), want %s (%s)", tt.accept, got.format, got.mediaType, tt.want, tt.mediaType)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package negotiate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// toTree converts obj to plain maps, slices and scalars by way of its JSON
+// encoding, so every format uses the JSON field names and honours json:"-"
+// and custom MarshalJSON methods. Integers stay integers.
+func toTree(obj any) (any, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return numbers(tree), nil
+}
+
+// numbers replaces json.Number leaves with int64 or float64.
+func numbers(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			x[k] = numbers(e)
+		}
+	case []any:
+		for i, e := range x {
+			x[i] = numbers(e)
+		}
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64()
+		return f
+	}
+	return v
+}
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// coerce adapts a decoded body to the JSON shape of t. XML and CSV carry
+// every value as text and YAML guesses scalar types, so strings are parsed
+// into the numbers and booleans t expects, scalars become strings where t
+// wants text, and a "|"-separated CSV cell becomes a list.
+func coerce(v any, t reflect.Type) any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		// The type reads its own JSON form (time.Time takes RFC 3339 text).
+		return v
+	}
+
+	s, isString := v.(string)
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return v
+		}
+		fields := jsonFields(t)
+		for k, e := range m {
+			if ft, ok := lookupField(fields, k); ok {
+				m[k] = coerce(e, ft)
+			}
+		}
+		return m
+
+	case reflect.Map:
+		if m, ok := v.(map[string]any); ok {
+			for k, e := range m {
+				m[k] = coerce(e, t.Elem())
+			}
+		}
+		return v
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// Bytes travel as base64 text.
+			return v
+		}
+		var items []any
+		switch x := v.(type) {
+		case []any:
+			items = x
+		case string:
+			items = []any{}
+			for _, item := range strings.Split(x, "|") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		case nil:
+			return nil
+		default:
+			items = []any{x}
+		}
+		for i, e := range items {
+			items[i] = coerce(e, t.Elem())
+		}
+		return items
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isString {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil
+			}
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return i
+			}
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if isString {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil
+			}
+			if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+				return u
+			}
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if isString {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil
+			}
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f
+			}
+		}
+
+	case reflect.Bool:
+		if isString {
+			if s = strings.TrimSpace(s); s == "" {
+				return nil
+			}
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+
+	case reflect.String:
+		switch x := v.(type) {
+		case int64, uint64, float64, bool:
+			return fmt.Sprint(x)
+		}
+	}
+	return v
+}
+
+// jsonFields maps the JSON names of t's fields to their types, including
+// the fields of embedded structs, as encoding/json sees them.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range jsonFields(ft) {
+					if _, taken := fields[k]; !taken {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupField finds a field by JSON name, falling back to the
+// case-insensitive match encoding/json also accepts.
+func lookupField(fields map[string]reflect.Type, name string) (reflect.Type, bool) {
+	if t, ok := fields[name]; ok {
+		return t, true
+	}
+	for k, t := range fields {
+		if strings.EqualFold(k, name) {
+			return t, true
+		}
+	}
+	return nil, false
+}
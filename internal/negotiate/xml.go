@@ -0,0 +1,195 @@
+package negotiate
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// XML documents mirror the JSON shape: objects become elements named after
+// their keys (sorted), arrays hold one <item> per element, and scalars are
+// element text. Keys that are not valid XML names, such as thumbnail sizes,
+// are written as <entry key="150">.
+const (
+	xmlRoot  = "response"
+	xmlItem  = "item"
+	xmlEntry = "entry"
+)
+
+var xmlContentType = []string{"application/xml; charset=utf-8"}
+
+// xmlTree renders a tree from toTree as XML.
+type xmlTree struct {
+	data any
+}
+
+func (r xmlTree) WriteContentType(w http.ResponseWriter) {
+	if header := w.Header(); len(header["Content-Type"]) == 0 {
+		header["Content-Type"] = xmlContentType
+	}
+}
+
+func (r xmlTree) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := encodeXML(enc, xml.StartElement{Name: xml.Name{Local: xmlRoot}}, r.data); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+// xmlElement returns the start element for an object key.
+func xmlElement(key string) xml.StartElement {
+	if validXMLName(key) {
+		return xml.StartElement{Name: xml.Name{Local: key}}
+	}
+	return xml.StartElement{
+		Name: xml.Name{Local: xmlEntry},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}},
+	}
+}
+
+func encodeXML(enc *xml.Encoder, start xml.StartElement, v any) error {
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch x := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := encodeXML(enc, xmlElement(k), x[k]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, e := range x {
+			if err := encodeXML(enc, xml.StartElement{Name: xml.Name{Local: xmlItem}}, e); err != nil {
+				return err
+			}
+		}
+	case nil:
+	case float64:
+		if err := enc.EncodeToken(xml.CharData(strconv.FormatFloat(x, 'f', -1, 64))); err != nil {
+			return err
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(x))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// validXMLName reports whether name can be used as an element name as is.
+func validXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// decodeXML reads a document written the same way back into a tree. The
+// root element's name is ignored. Elements without child elements become
+// strings; elements whose children are all <item> become arrays; repeated
+// child elements are collected into an array.
+func decodeXML(r io.Reader) (any, error) {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil, errors.New("empty XML document")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tok.(xml.StartElement); ok {
+			return decodeXMLElement(dec)
+		}
+	}
+}
+
+// decodeXMLElement decodes the content of the element just started.
+func decodeXMLElement(dec *xml.Decoder) (any, error) {
+	var (
+		text     strings.Builder
+		keys     []string
+		values   = map[string]any{}
+		children int
+		items    = []any{}
+	)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			text.Write(t)
+		case xml.StartElement:
+			v, err := decodeXMLElement(dec)
+			if err != nil {
+				return nil, err
+			}
+			children++
+			if t.Name.Local == xmlItem {
+				items = append(items, v)
+			}
+			key := t.Name.Local
+			if key == xmlEntry {
+				for _, a := range t.Attr {
+					if a.Name.Local == "key" {
+						key = a.Value
+					}
+				}
+			}
+			if prev, seen := values[key]; !seen {
+				keys = append(keys, key)
+				values[key] = v
+			} else if list, ok := prev.(repeated); ok {
+				values[key] = append(list, v)
+			} else {
+				values[key] = repeated{prev, v}
+			}
+		case xml.EndElement:
+			switch {
+			case children == 0:
+				return strings.TrimSpace(text.String()), nil
+			case len(items) == children:
+				return items, nil
+			}
+			out := make(map[string]any, len(keys))
+			for _, k := range keys {
+				if list, ok := values[k].(repeated); ok {
+					out[k] = []any(list)
+				} else {
+					out[k] = values[k]
+				}
+			}
+			return out, nil
+		}
+	}
+}
+
+// repeated collects the values of sibling elements sharing a name.
+type repeated []any
@@ -0,0 +1,82 @@
+// Package problem describes failed requests as RFC 7807 problem details.
+// Handlers report errors with c.Error; middleware.Problems turns the last
+// one into an application/problem+json response.
+package problem
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ContentType is the media type of a rendered Problem.
+const ContentType = "application/problem+json"
+
+// DefaultType is the problem type of errors described by their status alone.
+const DefaultType = "about:blank"
+
+// internalDetail replaces the message of unexpected errors when internals
+// must not be exposed.
+const internalDetail = "An unexpected error occurred. Quote the request ID when reporting it."
+
+// Problem is an RFC 7807 problem detail. Detail is shown to clients, so it
+// must never carry internal messages such as database errors.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// RequestID correlates the response with the server logs.
+	RequestID string `json:"request_id,omitempty"`
+
+	// cause is the underlying error, kept for logging.
+	cause error
+}
+
+// New returns a Problem with the given status and a detail that is safe to
+// show to clients.
+func New(status int, detail string) *Problem {
+	return &Problem{Type: DefaultType, Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) Unwrap() error {
+	return p.cause
+}
+
+// Mapping assigns an HTTP status to the errors matching Err (errors.Is).
+// Their messages are shown to clients as the problem detail.
+type Mapping struct {
+	Err    error
+	Status int
+}
+
+// From describes err as a Problem. A *Problem is used as is; an error
+// matching one of mappings gets the mapped status and its own message;
+// anything else is a 500 whose message is only shown if expose is set.
+func From(err error, mappings []Mapping, expose bool) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		out := *p
+		return &out
+	}
+	for _, m := range mappings {
+		if errors.Is(err, m.Err) {
+			p = New(m.Status, err.Error())
+			p.cause = err
+			return p
+		}
+	}
+	p = New(http.StatusInternalServerError, internalDetail)
+	if expose {
+		p.Detail = err.Error()
+	}
+	p.cause = err
+	return p
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for keys that are empty or escape the storage root.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Info describes a stored blob.
+type Info struct {
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage stores opaque blobs under slash-separated keys such as
+// "covers/12/original". Implementations must be safe for concurrent use.
+type Storage interface {
+	// Put stores the contents of r under key, replacing any existing blob.
+	Put(key string, r io.Reader) error
+	// Get opens the blob stored under key. The caller must close it.
+	Get(key string) (io.ReadCloser, Info, error)
+	// Delete removes the blob under key. Missing blobs are not an error.
+	Delete(key string) error
+	// DeletePrefix removes every blob whose key starts with prefix + "/".
+	DeletePrefix(prefix string) error
+}